@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/bits"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// Variants of -also-ip.
+const (
+	// variantHost is the IP of the host asked for with its name in the
+	// Host header, as a virtual host
+	variantHost = "host"
+	// variantIP is the bare IP, as a client without the name sees it
+	variantIP = "ip"
+)
+
+// ipVariantThreshold is how many bits of their perceptual hashes two
+// screenshots may differ in before a variant counts as looking different.
+const ipVariantThreshold = 10
+
+// ipURL returns the URL of res with its host replaced by the address Chrome
+// loaded it from, and the host it replaced. There is none for pages that go
+// to an IP already.
+func ipURL(res *result) (string, string, bool) {
+	if res.IP == "" {
+		return "", "", false
+	}
+	u, err := url.Parse(res.URL)
+	if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+		return "", "", false
+	}
+	host, port := u.Host, u.Port()
+	u.Host = net.JoinHostPort(res.IP, port)
+	if port == "" {
+		// keeping the brackets of an IPv6 address
+		u.Host = strings.TrimSuffix(u.Host, ":")
+	}
+	return u.String(), host, true
+}
+
+// compareVariant records in v, a successful capture of -also-ip, what
+// differs from orig, the page it is a variant of.
+func compareVariant(orig, v *result) {
+	if v.Status != orig.Status {
+		v.Differs = append(v.Differs, "status")
+	}
+	if v.Title != orig.Title {
+		v.Differs = append(v.Differs, "title")
+	}
+	a, errA := screenshot.DHash(orig.Image)
+	b, errB := screenshot.DHash(v.Image)
+	if errA == nil && errB == nil && bits.OnesCount64(a^b) > ipVariantThreshold {
+		v.Differs = append(v.Differs, "screenshot")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// archiver moves finished files into a single tar, tar.gz or zip archive as
+// they are written, so a run leaves one file instead of one per artifact. It
+// is safe for concurrent use.
+type archiver struct {
+	root string // local directory entries are named relative to
+
+	mu  sync.Mutex
+	f   *os.File
+	gz  *gzip.Writer
+	tw  *tar.Writer
+	zw  *zip.Writer
+	err error // the first write error, after which the archive is broken
+}
+
+// newArchiver creates the archive at path, whose format is given by its
+// extension: .tar, .tar.gz, .tgz or .zip.
+func newArchiver(path, root string) (*archiver, error) {
+	a := &archiver{root: root}
+	name := strings.ToLower(path)
+	var open func(w io.Writer)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		open = func(w io.Writer) {
+			a.gz = gzip.NewWriter(w)
+			a.tw = tar.NewWriter(a.gz)
+		}
+	case strings.HasSuffix(name, ".tar"):
+		open = func(w io.Writer) { a.tw = tar.NewWriter(w) }
+	case strings.HasSuffix(name, ".zip"):
+		open = func(w io.Writer) { a.zw = zip.NewWriter(w) }
+	default:
+		return nil, fmt.Errorf("unknown archive format of %q, must end in .tar, .tar.gz, .tgz or .zip", path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	a.f = f
+	open(f)
+	return a, nil
+}
+
+// add moves files, relative to dir, into the archive.
+func (a *archiver) add(dir string, files ...string) error {
+	return a.write(dir, true, files...)
+}
+
+// addKeep copies files into the archive like add, keeping them on disk, like
+// results.jsonl which -resume reads.
+func (a *archiver) addKeep(dir string, files ...string) error {
+	return a.write(dir, false, files...)
+}
+
+func (a *archiver) write(dir string, remove bool, files ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+		path := filepath.Join(dir, f)
+		if err := a.writeFile(path); err != nil {
+			return fmt.Errorf("archiving %s: %w", path, err)
+		}
+		if remove {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *archiver) writeFile(path string) error {
+	if a.err != nil {
+		return a.err
+	}
+	name, err := filepath.Rel(a.root, path)
+	if err != nil {
+		return err
+	}
+	name = filepath.ToSlash(name)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer
+	if a.tw != nil {
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if a.err = a.tw.WriteHeader(hdr); a.err != nil {
+			return a.err
+		}
+		w = a.tw
+	} else {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		hdr.Method = zip.Deflate
+		if w, a.err = a.zw.CreateHeader(hdr); a.err != nil {
+			return a.err
+		}
+	}
+	// a partly written entry leaves the archive unusable
+	_, a.err = io.Copy(w, f)
+	return a.err
+}
+
+// close finishes the archive.
+func (a *archiver) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var err error
+	if a.tw != nil {
+		err = a.tw.Close()
+	}
+	if a.zw != nil {
+		err = a.zw.Close()
+	}
+	if a.gz != nil {
+		if gzErr := a.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if fErr := a.f.Close(); err == nil {
+		err = fErr
+	}
+	return err
+}
@@ -0,0 +1,815 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// errAborted ends a run a capture failed with the abort-run policy in.
+var errAborted = errors.New("run aborted")
+
+// batch captures a list of URLs into an output directory.
+type batch struct {
+	c             *screenshot.Capturer
+	logger        *slog.Logger
+	stderr        *statusWriter
+	ext           string
+	inputFormat   string
+	namer         *screenshot.Namer
+	concurrency   int
+	jsonOut       bool
+	resume        bool
+	quiet         bool
+	statsInterval time.Duration
+
+	// hostConcurrency is the most captures of one host running at once, 0
+	// for no limit
+	hostConcurrency int
+	// crawlDepth is how many links to follow from the input pages within
+	// their origin, at most crawlMaxPages pages a host
+	crawlDepth    int
+	crawlMaxPages int
+	// expand lists where else to find pages of the input's sites, at most
+	// expandMax a host, fetched through proxy
+	expand    []string
+	expandMax int
+	proxy     string
+	// disk, if set, pauses the captures while the output disk is full
+	disk *diskGuard
+	// alsoIP also captures the IP of every page, with and without its host
+	alsoIP bool
+	// ports, if set, are tried for every host of the input without a
+	// scheme or port
+	ports []string
+	// crossOrigin flags the pages redirecting to another host
+	crossOrigin bool
+	// sinks are the -sink outputs every result is written to, in order,
+	// webhook the URL of the webhook sink
+	sinks   []string
+	webhook string
+	// dedupe skips URLs seen before in the input, normalize rewrites them
+	// with normalizeURL first
+	dedupe    bool
+	normalize bool
+	// tags are added to every result of the run, ahead of those of the
+	// input
+	tags []string
+
+	cluster          bool
+	clusterThreshold int
+	// thumbWidth is the width of the thumbnails written to thumbs/, none
+	// are written if 0
+	thumbWidth int
+	// ocr, if set, extracts the text of every screenshot
+	ocr *ocr
+	// ipInfo, if set, adds the addresses and network of every host
+	ipInfo *ipInfo
+
+	// db, if set, indexes every capture
+	db *resultDB
+	// uploader, if set, uploads everything written to the output directory
+	uploader *uploader
+	// archive, if set, takes everything written for a page out of the
+	// output directory
+	archive *archiver
+	stats   *metrics
+	// notifier, if set, is told about finished runs and, with monitor,
+	// changed pages
+	notifier *notifier
+
+	// changes is set with -only-changed
+	changes *changeTracker
+	// watch, if set, follows the changes of the input file of monitor
+	watch *inputWatcher
+	// control, if set, pauses and resizes the run and pushes URLs to the
+	// front of its queue
+	control *controller
+}
+
+// run captures every URL read from in, in inputFormat, into output. fromFile
+// is set if in is a file, whose remaining lines are checkpointed on
+// interrupt, and total is its number of lines if known.
+func (b *batch) run(ctx context.Context, output string, in io.Reader, fromFile bool, total int) error {
+	// a capture failing with the abort-run policy ends the run like an
+	// interrupt
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	var results []result
+	if b.resume {
+		// keep what was captured by earlier runs in the gallery
+		var err error
+		if results, err = loadResults(output); err != nil {
+			return err
+		}
+	}
+	var clusterer *screenshot.Clusterer
+	if b.cluster {
+		// cluster numbers are only stable within a run
+		clusterer = &screenshot.Clusterer{Threshold: b.clusterThreshold}
+	}
+	previous := make(map[string]bool, len(results))
+	for i, r := range results {
+		previous[r.URL] = true
+		if r.Input != "" {
+			previous[r.Input] = true
+		}
+		if clusterer != nil {
+			if hash, err := strconv.ParseUint(r.PHash, 16, 64); err == nil {
+				results[i].Cluster = clusterer.Assign(hash)
+			}
+		}
+	}
+
+	// targets are the URLs of structured input with options of their own
+	var targets map[string]*target
+	switch b.inputFormat {
+	case "", inputPlain:
+	case inputJSON, inputCSV:
+		list, err := parseTargets(b.inputFormat, in)
+		if err != nil {
+			return err
+		}
+		targets = make(map[string]*target, len(list))
+		urls := make([]string, len(list))
+		for i, t := range list {
+			urls[i] = t.URL
+			if b.normalize {
+				// looked up by the URL as it is captured
+				urls[i] = normalizeURL(t.URL)
+			}
+			// the last record of a URL wins
+			targets[urls[i]] = t
+		}
+		in, total = strings.NewReader(strings.Join(urls, "\n")), len(urls)
+	default:
+		urls, err := parseInput(b.inputFormat, in)
+		if err != nil {
+			return err
+		}
+		in, total = strings.NewReader(strings.Join(urls, "\n")), len(urls)
+	}
+
+	var crawl *crawler
+	if b.crawlDepth > 0 {
+		crawl = newCrawler(b.crawlDepth, b.crawlMaxPages, b.normalize)
+		// pages are found as the run goes
+		total = 0
+	}
+	var expand *expander
+	if len(b.expand) > 0 {
+		var err error
+		if expand, err = newExpander(b.expand, b.expandMax, b.proxy, b.logger); err != nil {
+			return err
+		}
+		total = 0
+	}
+	if b.alsoIP {
+		// not every page has an IP to capture as well
+		total = 0
+	}
+	var ports *portExpander
+	if len(b.ports) > 0 {
+		ports = newPortExpander(b.ports, b.logger)
+		// hosts become several URLs
+		total = 0
+	}
+	// the tags of plain input lines and crawled pages
+	tags := newTagIndex()
+	prog := newProgress(total)
+	sinks, err := b.openSinks(output)
+	if err != nil {
+		return err
+	}
+
+	var resultsMu sync.Mutex
+	addResult := func(res result) {
+		resultsMu.Lock()
+		results = append(results, res)
+		resultsMu.Unlock()
+	}
+
+	reader := readInput(in, b.stats, prog)
+	lines := reader.lines
+	// malformed counts the lines skipped for not being URLs
+	var malformed int
+
+	jobs := make(chan string)
+	var unprocessed []string
+	interrupted := func(pending ...string) {
+		for _, requestURL := range pending {
+			// keeping their tags for the next run
+			if t := tags.get(requestURL); len(t) > 0 {
+				requestURL += " " + strings.Join(t, ",")
+			}
+			unprocessed = append(unprocessed, requestURL)
+		}
+		if fromFile {
+			// stdin might never end, but the rest of a file can be
+			// saved as well
+			for requestURL := range lines {
+				unprocessed = append(unprocessed, requestURL)
+			}
+		}
+	}
+	sched := newScheduler(b.hostConcurrency)
+	seen := map[string]bool{}
+	go func() {
+		defer close(jobs)
+		input := lines
+		for {
+			// running captures of a crawl may still find pages
+			growing := crawl != nil || expand != nil
+			for _, line := range b.control.take() {
+				requestURL, lineTags := splitTags(line)
+				if b.normalize {
+					requestURL = normalizeURL(requestURL)
+				}
+				tags.set(requestURL, lineTags)
+				candidates := []string{requestURL}
+				if ports != nil {
+					candidates = ports.expand(requestURL)
+				}
+				// in the order of the ports
+				for i := len(candidates) - 1; i >= 0; i-- {
+					tags.inherit(candidates[i], lineTags)
+					b.logger.Debug("pushed to the front", "url", candidates[i])
+					b.stats.queue()
+					if crawl != nil {
+						crawl.visit(candidates[i])
+					}
+					sched.push(candidates[i])
+				}
+			}
+			if input == nil && (!growing && sched.empty() || growing && sched.idle()) {
+				return
+			}
+			var read <-chan string
+			if !sched.full() {
+				read = input
+			}
+			var send chan<- string
+			var recheck <-chan time.Time
+			next, ok := sched.next()
+			if ok && b.control.ready() {
+				if b.disk == nil || b.disk.ok() {
+					send = jobs
+				} else {
+					recheck = time.After(diskPoll)
+				}
+			}
+
+			var requestURL string
+			select {
+			case line, ok := <-read:
+				if !ok {
+					input = nil
+					continue
+				}
+				line = strings.TrimSpace(line)
+				if line == "" || strings.HasPrefix(line, "#") {
+					b.stats.inputLine("blank")
+					prog.skip()
+					continue
+				}
+				var lineTags []string
+				requestURL, lineTags = splitTags(line)
+				if err := checkURL(requestURL); err != nil {
+					b.logger.Debug("skipping, malformed input", "line", line, "err", err)
+					b.stats.inputLine("malformed")
+					malformed++
+					prog.skip()
+					continue
+				}
+				if b.normalize {
+					requestURL = normalizeURL(requestURL)
+				}
+				tags.set(requestURL, lineTags)
+			case send <- next:
+				sched.start(next)
+				b.control.begin()
+				continue
+			case <-sched.wake:
+				continue
+			case <-b.control.woken():
+				continue
+			case <-recheck:
+				continue
+			case <-ctx.Done():
+				pending := sched.drain()
+				for range pending {
+					b.stats.cancel()
+				}
+				interrupted(pending...)
+				return
+			}
+
+			if b.dedupe {
+				if seen[requestURL] {
+					b.logger.Debug("skipping, duplicate", "url", requestURL)
+					prog.skip()
+					b.stats.skip()
+					continue
+				}
+				seen[requestURL] = true
+			}
+			if b.resume {
+				if previous[requestURL] {
+					b.logger.Debug("skipping, already captured", "url", requestURL)
+					prog.skip()
+					b.stats.skip()
+					continue
+				}
+				if path, ok := captured(output, b.ext, b.namer, requestURL); ok {
+					b.logger.Debug("skipping, already captured", "url", requestURL)
+					addResult(result{Result: screenshot.Result{URL: requestURL}, Screenshot: path})
+					prog.skip()
+					b.stats.skip()
+					continue
+				}
+			}
+
+			candidates := []string{requestURL}
+			if ports != nil {
+				candidates = ports.expand(requestURL)
+			}
+			for _, candidate := range candidates {
+				if candidate != requestURL {
+					tags.inherit(candidate, tags.get(requestURL))
+				}
+				b.stats.queue()
+				if crawl != nil {
+					crawl.visit(candidate)
+				}
+				sched.add(candidate)
+			}
+		}
+	}()
+
+	stopReport := make(chan struct{})
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		if !b.quiet {
+			prog.report(stopReport, b.stderr, b.logger, b.statsInterval)
+		}
+	}()
+
+	// in-flight captures are not cancelled on interrupt
+	capture := func(requestURL string) (result, error) {
+		var o screenshot.URLOptions
+		var name string
+		if t := targets[requestURL]; t != nil {
+			o, name = t.opts, t.Name
+		}
+		o.Tags = mergeTags(b.tags, o.Tags, tags.get(requestURL))
+		shot, err := b.c.With(o).Capture(context.Background(), requestURL)
+		return result{Result: shot, name: name}, err
+	}
+	// the variants of -also-ip are captured along with their page
+	captureVariant := func(orig result, ipURL, variant, host string) (result, error) {
+		var o screenshot.URLOptions
+		if t := targets[orig.URL]; t != nil {
+			o = t.opts
+		} else if t := targets[orig.Input]; t != nil {
+			o = t.opts
+		}
+		if variant == variantHost {
+			o.HostHeader = host
+		}
+		o.Tags = orig.Tags
+		shot, err := b.c.With(o).Capture(context.Background(), ipURL)
+		res := result{Result: shot, IPVariant: variant, VariantOf: orig.URL}
+		if variant == variantHost {
+			// the same URL as the bare IP
+			if path, perr := b.namer.Filepath("", &res.Result); perr == nil {
+				res.name = strings.TrimPrefix(path, "/") + "_" + strings.ReplaceAll(host, ":", "-")
+			}
+		}
+		if err == nil {
+			compareVariant(&orig, &res)
+		}
+		return res, err
+	}
+	// release queues the pages a scheduled capture leads to and lets the
+	// next capture of its host start
+	release := func(res *result, err error) {
+		if crawl != nil && ctx.Err() == nil {
+			// queued before the capture is done, so the run does not end
+			// in between
+			for _, link := range crawl.follow(res) {
+				tags.inherit(link, res.Tags)
+				b.stats.queue()
+				sched.add(link)
+			}
+		}
+		if expand != nil && err == nil && res.Depth == 0 && ctx.Err() == nil {
+			for _, page := range expand.expand(ctx, res) {
+				tags.inherit(page, res.Tags)
+				if crawl != nil {
+					crawl.visit(page)
+				}
+				b.stats.queue()
+				sched.add(page)
+			}
+		}
+		// a URL without a scheme comes back as the one probed
+		if res.Input != "" {
+			sched.done(res.Input)
+		} else {
+			sched.done(res.URL)
+		}
+	}
+	handle := func(res result, err error) {
+		if b.ipInfo != nil {
+			b.ipInfo.enrich(context.Background(), &res)
+		}
+		var saveErr error
+		if err != nil {
+			res.Error = err.Error()
+		} else if b.changes != nil {
+			b.changes.unchanged(&res)
+		}
+		if err == nil && b.crossOrigin {
+			flagCrossOrigin(&res)
+		}
+		if err == nil && clusterer != nil {
+			saveErr = assignCluster(clusterer, &res)
+		}
+		for _, s := range sinks {
+			saveErr = errors.Join(saveErr, s.write(&res))
+		}
+		if err == nil && saveErr == nil && b.changes != nil {
+			b.changes.record(&res)
+		}
+		logResult(b.logger, &res, err, saveErr)
+		b.stats.done(&res, err, saveErr)
+		if err != nil {
+			prog.failure(screenshot.ErrorCategory(err))
+		} else if saveErr != nil {
+			prog.failure("save")
+		}
+		if b.notifier != nil {
+			b.notifier.observe(&res, err)
+		}
+		err = errors.Join(err, saveErr)
+		if err != nil {
+			res.Error = err.Error()
+		}
+		prog.done(err)
+
+		if b.archive != nil && !res.Unchanged {
+			if err := b.archive.add(output, res.files()...); err != nil {
+				b.logger.Error("archiving result", "url", res.URL, "err", err)
+			}
+		}
+		addResult(res)
+	}
+	// onError applies the -on-error policy of the category of a failed
+	// capture, reporting whether it is skipped
+	onError := func(res *result, err error) bool {
+		if err == nil {
+			return false
+		}
+		category := screenshot.ErrorCategory(err)
+		switch b.c.Policy(err) {
+		case screenshot.PolicySkip:
+			b.logger.Info("skipping failed capture", "url", res.URL, "category", category, "err", err)
+			prog.failure(category)
+			prog.skip()
+			b.stats.cancel()
+			b.stats.skip()
+			return true
+		case screenshot.PolicyAbort:
+			b.logger.Error("aborting run", "url", res.URL, "category", category, "err", err)
+			abort(fmt.Errorf("%w: %s failed: %w", errAborted, res.URL, err))
+		}
+		return false
+	}
+	work := func(requestURL string) {
+		if b.watch != nil && b.watch.removed(requestURL) {
+			b.logger.Debug("skipping, removed from the input", "url", requestURL)
+			sched.done(requestURL)
+			prog.skip()
+			b.stats.cancel()
+			b.stats.skip()
+			return
+		}
+		res, err := capture(requestURL)
+		if ports != nil && !ports.keep(requestURL, &res, &err) {
+			sched.done(requestURL)
+			prog.skip()
+			b.stats.cancel()
+			return
+		}
+		release(&res, err)
+		if onError(&res, err) {
+			return
+		}
+		handle(res, err)
+		if !b.alsoIP || err != nil || ctx.Err() != nil {
+			return
+		}
+		if ipURL, host, ok := ipURL(&res); ok {
+			for _, variant := range []string{variantHost, variantIP} {
+				b.stats.queue()
+				if vres, verr := captureVariant(res, ipURL, variant, host); !onError(&vres, verr) {
+					handle(vres, verr)
+				}
+			}
+		}
+	}
+	// workers are added as -control raises the concurrency, those above a
+	// lowered one wait for jobs the controller does not start
+	exited := make(chan struct{})
+	spawned, running := 0, 0
+	for {
+		for ; spawned < b.control.workers(b.concurrency); spawned++ {
+			running++
+			go func() {
+				defer func() { exited <- struct{}{} }()
+				for requestURL := range jobs {
+					work(requestURL)
+					b.control.end()
+				}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+		select {
+		case <-exited:
+			running--
+		case <-b.control.resized():
+		}
+	}
+
+	close(stopReport)
+	<-reported
+	if n := malformed + int(reader.tooLong.Load()); n > 0 {
+		b.logger.Warn("skipped malformed input lines", "lines", n)
+	}
+	if err := reader.failed(); err != nil {
+		b.logger.Error("reading input", "err", err)
+	}
+	if counts := prog.categories(); len(counts) > 0 {
+		var attrs []any
+		for _, category := range slices.Sorted(maps.Keys(counts)) {
+			attrs = append(attrs, category, counts[category])
+		}
+		b.logger.Warn("errors by category", attrs...)
+	}
+
+	if b.changes != nil {
+		if err := b.changes.save(); err != nil {
+			b.logger.Error("writing hashes", "err", err)
+		}
+		// the gallery only shows what changed
+		changed := results[:0]
+		for _, r := range results {
+			if !r.Unchanged {
+				changed = append(changed, r)
+			}
+		}
+		results = changed
+	}
+
+	if ctx.Err() != nil && !hasSink(b.sinks, sinkFS) {
+		b.logger.Warn("interrupted", "unprocessed", len(unprocessed))
+	} else if ctx.Err() != nil {
+		if err := writeCheckpoint(b.logger, output, unprocessed); err != nil {
+			b.logger.Error("writing checkpoint", "err", err)
+		}
+	}
+	for _, s := range sinks {
+		if err := s.finish(results, ctx.Err() != nil); err != nil {
+			b.logger.Error("finishing output", "err", err)
+		}
+	}
+
+	if b.notifier != nil {
+		b.notifier.finished(output, prog)
+		b.notifier.wait()
+	}
+	if b.archive != nil {
+		files := []string{"index.html"}
+		if !b.jsonOut {
+			files = append(files, "results.jsonl")
+		}
+		if ctx.Err() != nil {
+			files = append(files, "checkpoint.txt")
+		}
+		if err := b.archive.addKeep(output, files...); err != nil {
+			b.logger.Error("archiving results", "err", err)
+		}
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, errAborted) {
+		return cause
+	}
+	return nil
+}
+
+// monitor captures the input into a new timestamped directory under output
+// every interval until ctx is done. A file given as input is read again every
+// time, stdin only once. Plain input files are watched as well, the URLs
+// added to them are captured into the last round's directory right away and
+// the removed ones skipped.
+func (b *batch) monitor(ctx context.Context, output, inFile string, interval time.Duration) error {
+	var stdin []byte
+	if inFile == "" {
+		var err error
+		if stdin, err = io.ReadAll(os.Stdin); err != nil {
+			return err
+		}
+	}
+	if b.notifier != nil {
+		b.notifier.track = true
+	}
+	var changed <-chan struct{}
+	if inFile != "" && (b.inputFormat == "" || b.inputFormat == inputPlain) {
+		w, err := newInputWatcher(inFile, b.logger)
+		if err != nil {
+			return fmt.Errorf("watching input: %w", err)
+		}
+		defer w.close()
+		b.watch, changed = w, w.changed
+	}
+
+	for {
+		dir := filepath.Join(output, time.Now().Format("20060102T150405"))
+		if hasSink(b.sinks, sinkFS) {
+			if err := createOutputDir(dir); err != nil {
+				return err
+			}
+		}
+		if b.changes != nil {
+			b.changes.round = dir
+		}
+		if b.watch != nil {
+			if err := b.watch.start(); err != nil {
+				return err
+			}
+		}
+		if err := b.runRound(ctx, dir, inFile, stdin); err != nil {
+			return err
+		}
+
+		b.logger.Info("round done", "dir", dir, "next", time.Now().Add(interval).Format(time.RFC3339))
+		next := time.After(interval)
+	wait:
+		for {
+			select {
+			case <-next:
+				break wait
+			case <-changed:
+				added := b.watch.added()
+				if len(added) == 0 {
+					continue
+				}
+				b.logger.Info("input changed", "added", len(added), "dir", dir)
+				// appended to the round, keeping what it captured
+				more := *b
+				more.resume = true
+				if err := more.run(ctx, dir, strings.NewReader(strings.Join(added, "\n")), false, len(added)); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// runRound runs a single round of monitor, reading the input from inFile or,
+// if there is none, from stdin read before.
+func (b *batch) runRound(ctx context.Context, dir, inFile string, stdin []byte) error {
+	if inFile == "" {
+		return b.run(ctx, dir, bytes.NewReader(stdin), false, bytes.Count(stdin, []byte("\n")))
+	}
+	file, err := os.Open(inFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	total, err := countLines(inFile)
+	if err != nil {
+		return err
+	}
+	return b.run(ctx, dir, file, true, total)
+}
+
+// savedShot is the last screenshot saved for a URL, with its path relative
+// to the output directory.
+type savedShot struct {
+	Hash string `json:"hash"` // hex SHA-256 of the image or DOM
+	Path string `json:"path"`
+}
+
+// changeTracker remembers the screenshots saved by earlier runs, and the
+// rounds of monitor, to skip saving those identical to the previous one of
+// the same page. They are kept in hashes.json in the output directory. It is
+// safe for concurrent use within a run.
+type changeTracker struct {
+	output string
+	useDOM bool   // compare the DOM instead of the image
+	round  string // output directory of the current round
+
+	mu   sync.Mutex
+	last map[string]savedShot
+}
+
+func newChangeTracker(output string, useDOM bool) (*changeTracker, error) {
+	t := &changeTracker{output: output, useDOM: useDOM, round: output, last: map[string]savedShot{}}
+	data, err := os.ReadFile(filepath.Join(output, "hashes.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &t.last); err != nil {
+		return nil, fmt.Errorf("reading hashes.json: %w", err)
+	}
+	return t, nil
+}
+
+// key identifies the page of res across runs: its URL, and the variant of
+// -also-ip or the name given in the input, which can share it.
+func (t *changeTracker) key(res *result) string {
+	key := res.URL
+	if res.IPVariant != "" {
+		key += " " + res.IPVariant
+	}
+	if res.name != "" {
+		key += " " + res.name
+	}
+	return key
+}
+
+func (t *changeTracker) hash(res *result) string {
+	sum := res.ImageHash
+	if t.useDOM {
+		sum = sha256.Sum256([]byte(res.DOM))
+	}
+	return hex.EncodeToString(sum[:])
+}
+
+// unchanged reports whether the screenshot in res is identical to the
+// previous one of its page. If so res is pointed at the earlier file.
+func (t *changeTracker) unchanged(res *result) bool {
+	hash := t.hash(res)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if prev, ok := t.last[t.key(res)]; ok && prev.Hash == hash {
+		rel, _ := filepath.Rel(t.round, filepath.Join(t.output, filepath.FromSlash(prev.Path)))
+		res.Screenshot = filepath.ToSlash(rel)
+		res.Unchanged = true
+		return true
+	}
+	return false
+}
+
+// record remembers the screenshot the fs sink saved for res, for the next
+// run to compare with.
+func (t *changeTracker) record(res *result) {
+	if res.Unchanged || res.Screenshot == "" {
+		return
+	}
+	hash := t.hash(res)
+	rel, err := filepath.Rel(t.output, filepath.Join(t.round, filepath.FromSlash(res.Screenshot)))
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last[t.key(res)] = savedShot{Hash: hash, Path: filepath.ToSlash(rel)}
+}
+
+// save writes the hashes for the next run.
+func (t *changeTracker) save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, err := json.Marshal(t.last)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.output, "hashes.json"), data, 0644)
+}
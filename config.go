@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rcFile is the config file in the home directory read when -config is not
+// given.
+const rcFile = ".screenshotrc"
+
+// presets are the built in -preset flag values. Presets of the same name in a
+// config file replace them.
+var presets = map[string]map[string]any{
+	// many hosts quickly, as for a first look at a scope
+	"recon-fast": {
+		"width":        1280,
+		"height":       800,
+		"format":       "jpeg",
+		"quality":      60,
+		"wait-until":   "domcontentloaded",
+		"timeout":      "15s",
+		"retries":      0,
+		"block":        "fonts,media,analytics",
+		"detect-blank": true,
+	},
+	// full pages as they look to visitors, for reports
+	"report-quality": {
+		"width":            1920,
+		"height":           1080,
+		"scale":            2,
+		"fullpage":         true,
+		"format":           "png",
+		"wait-until":       "networkidle",
+		"delay":            "2s",
+		"scroll":           true,
+		"dismiss-overlays": true,
+		"block":            "analytics",
+	},
+}
+
+// config is a -config file or ~/.screenshotrc, flag names without the dash
+// with their values, like
+//
+//	concurrency: 8
+//	header:
+//	  - "X-Scan: 1"
+//	preset: mine
+//	presets:
+//	  mine:
+//	    width: 1440
+//	    fullpage: true
+//
+// and presets of the same to choose from with -preset.
+type config struct {
+	Flags   map[string]any            `yaml:",inline"`
+	Presets map[string]map[string]any `yaml:"presets"`
+}
+
+// applyConfig sets the flags not given on the command line from the config
+// file at path, or ~/.screenshotrc if path is empty and it exists, and from
+// the preset. Flags of the preset take precedence over the other flags of the
+// file.
+func applyConfig(path, preset string) error {
+	var cfg config
+	if err := readConfig(path, &cfg); err != nil {
+		return err
+	}
+	if preset == "" {
+		if p, ok := cfg.Flags["preset"].(string); ok {
+			preset = p
+		}
+	}
+	delete(cfg.Flags, "preset")
+	delete(cfg.Flags, "config")
+
+	values := cfg.Flags
+	if values == nil {
+		values = map[string]any{}
+	}
+	if preset != "" {
+		flags, ok := cfg.Presets[preset]
+		if !ok {
+			flags, ok = presets[preset]
+		}
+		if !ok {
+			return fmt.Errorf("unknown preset %q, must be one of %s", preset, strings.Join(presetNames(cfg.Presets), ", "))
+		}
+		for name, v := range flags {
+			values[name] = v
+		}
+	}
+
+	// aliases like -c and -concurrency share the value they set
+	given := map[flag.Value]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Value] = true
+	})
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if f := flag.Lookup(name); f != nil && given[f.Value] {
+			continue
+		}
+		if err := setFlag(name, values[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readConfig reads the config file at path into cfg. Without a path, a
+// missing ~/.screenshotrc is not an error.
+func readConfig(path string, cfg *config) error {
+	optional := false
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, rcFile)
+		optional = true
+	}
+	data, err := os.ReadFile(path)
+	if optional && errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("reading config %s: %w", path, err)
+	}
+	return nil
+}
+
+// setFlag sets the flag name to the config value v. Every item of a list is
+// given to flags that can be repeated, other flags get them comma separated.
+func setFlag(name string, v any) error {
+	f := flag.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("unknown flag %q in config", name)
+	}
+	items, isList := v.([]any)
+	if !isList {
+		items = []any{v}
+	}
+	if _, repeated := f.Value.(*stringList); !repeated && isList {
+		joined := make([]string, len(items))
+		for i, item := range items {
+			joined[i] = fmt.Sprint(item)
+		}
+		items = []any{strings.Join(joined, ",")}
+	}
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if err := flag.Set(name, fmt.Sprint(item)); err != nil {
+			return fmt.Errorf("invalid value %v for flag %s in config: %w", item, name, err)
+		}
+	}
+	return nil
+}
+
+// presetNames lists the built in presets and those of a config file.
+func presetNames(own map[string]map[string]any) []string {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	for name := range own {
+		if _, ok := presets[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
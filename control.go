@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// controller lets a running batch be paused, resumed and resized, and URLs
+// be pushed to the front of its queue, through the HTTP API of -control. It
+// lasts across the rounds of monitor, URLs pushed in between are captured by
+// the next one. Its methods are safe for concurrent use and do nothing on a
+// nil controller.
+type controller struct {
+	// wake is signalled when a job may be started that could not before,
+	// resize when the concurrency changed
+	wake   chan struct{}
+	resize chan struct{}
+
+	mu          sync.Mutex
+	paused      bool
+	concurrency int
+	running     int      // jobs started and not done
+	urgent      []string // pushed lines not queued yet
+}
+
+func newController(concurrency int) *controller {
+	return &controller{
+		wake:        make(chan struct{}, 1),
+		resize:      make(chan struct{}, 1),
+		concurrency: concurrency,
+	}
+}
+
+func poke(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// ready reports whether the next job may start.
+func (c *controller) ready() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.paused && c.running < c.concurrency
+}
+
+// workers returns how many workers a batch should have, def without a
+// controller.
+func (c *controller) workers(def int) int {
+	if c == nil {
+		return def
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.concurrency
+}
+
+// begin records that a job was started, end that it is done.
+func (c *controller) begin() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.running++
+	c.mu.Unlock()
+}
+
+func (c *controller) end() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	poke(c.wake)
+}
+
+// woken returns the channel signalled when a job may be started, nil without
+// a controller.
+func (c *controller) woken() <-chan struct{} {
+	if c == nil {
+		return nil
+	}
+	return c.wake
+}
+
+// resized returns the channel signalled when the concurrency changed, nil
+// without a controller.
+func (c *controller) resized() <-chan struct{} {
+	if c == nil {
+		return nil
+	}
+	return c.resize
+}
+
+// take removes and returns the pushed input lines, those pushed last first
+// so they end up in the order given once each is put in front of the queue.
+func (c *controller) take() []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lines := c.urgent
+	c.urgent = nil
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+// controlStatus is the response of every request to the control API.
+type controlStatus struct {
+	Paused      bool `json:"paused"`
+	Concurrency int  `json:"concurrency"`
+	Running     int  `json:"running"`
+	Pushed      int  `json:"pushed"`
+}
+
+func (c *controller) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", c.handleStatus)
+	mux.HandleFunc("POST /pause", func(w http.ResponseWriter, r *http.Request) {
+		c.setPaused(true)
+		c.handleStatus(w, r)
+	})
+	mux.HandleFunc("POST /resume", func(w http.ResponseWriter, r *http.Request) {
+		c.setPaused(false)
+		c.handleStatus(w, r)
+	})
+	mux.HandleFunc("POST /concurrency", c.handleConcurrency)
+	mux.HandleFunc("POST /urls", c.handleURLs)
+	return mux
+}
+
+func (c *controller) handleStatus(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	status := controlStatus{Paused: c.paused, Concurrency: c.concurrency, Running: c.running, Pushed: len(c.urgent)}
+	c.mu.Unlock()
+	writeJSON(w, http.StatusOK, status)
+}
+
+// setPaused pauses or resumes starting jobs, those running are not stopped.
+func (c *controller) setPaused(paused bool) {
+	c.mu.Lock()
+	c.paused = paused
+	c.mu.Unlock()
+	poke(c.wake)
+}
+
+func (c *controller) handleConcurrency(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(r.FormValue("n"))
+	if err != nil || n < 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "n must be a number of at least 1"})
+		return
+	}
+	c.mu.Lock()
+	c.concurrency = n
+	c.mu.Unlock()
+	// running jobs above a lowered concurrency finish, but no new ones
+	// start until they did
+	poke(c.resize)
+	poke(c.wake)
+	c.handleStatus(w, r)
+}
+
+// handleURLs takes input lines, URLs with optional tags like in plain input,
+// to capture before everything queued. They are not deduplicated or
+// skipped on -resume. None are taken if any is malformed.
+func (c *controller) handleURLs(w http.ResponseWriter, r *http.Request) {
+	var lines []string
+	sc := bufio.NewScanner(r.Body)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		requestURL, _ := splitTags(line)
+		if err := checkURL(requestURL); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("line %d: %v", n, err)})
+			return
+		}
+		lines = append(lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	c.mu.Lock()
+	// pushed together with those not queued yet, ahead of them
+	c.urgent = append(lines, c.urgent...)
+	c.mu.Unlock()
+	poke(c.wake)
+	c.handleStatus(w, r)
+}
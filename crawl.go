@@ -0,0 +1,81 @@
+package main
+
+import "sync"
+
+// crawler follows the same origin links of captured pages, up to depth links
+// away from the input and at most perHost pages of every host beyond those of
+// the input. It is safe for concurrent use.
+type crawler struct {
+	depth     int
+	perHost   int // 0 for no limit
+	normalize bool
+
+	mu    sync.Mutex
+	pages map[string]crawlPage // every URL queued, input or crawled
+	hosts map[string]int       // crawled pages queued by host
+}
+
+// crawlPage is how a URL was found.
+type crawlPage struct {
+	depth int
+	from  string // the page linking to it, empty for the input
+}
+
+func newCrawler(depth, perHost int, normalize bool) *crawler {
+	return &crawler{
+		depth:     depth,
+		perHost:   perHost,
+		normalize: normalize,
+		pages:     map[string]crawlPage{},
+		hosts:     map[string]int{},
+	}
+}
+
+// visit records requestURL of the input, so links to it are not followed.
+func (cr *crawler) visit(requestURL string) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if _, ok := cr.pages[requestURL]; !ok {
+		cr.pages[requestURL] = crawlPage{}
+	}
+}
+
+// follow sets how res was found and returns the links of it to capture
+// next.
+func (cr *crawler) follow(res *result) []string {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	key := res.URL
+	if res.Input != "" {
+		key = res.Input
+	}
+	page := cr.pages[key]
+	res.Depth, res.CrawledFrom = page.depth, page.from
+	// the page may have been reached by another name
+	for _, u := range []string{res.URL, res.FinalURL} {
+		if _, ok := cr.pages[u]; u != "" && !ok {
+			cr.pages[u] = page
+		}
+	}
+	if page.depth >= cr.depth {
+		return nil
+	}
+
+	var next []string
+	for _, link := range res.Links {
+		if cr.normalize {
+			link = normalizeURL(link)
+		}
+		if _, ok := cr.pages[link]; ok {
+			continue
+		}
+		host := hostKey(link)
+		if cr.perHost > 0 && cr.hosts[host] >= cr.perHost {
+			continue
+		}
+		cr.hosts[host]++
+		cr.pages[link] = crawlPage{depth: page.depth + 1, from: res.URL}
+		next = append(next, link)
+	}
+	return next
+}
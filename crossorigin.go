@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// flagCrossOrigin sets the CrossOrigin fields of res if it ended up on
+// another host than the one asked for, through HTTP or client side
+// redirects. Moving to https or another port of the same host does not
+// count.
+func flagCrossOrigin(res *result) {
+	host := redirectHost(res.URL)
+	final := redirectHost(res.FinalURL)
+	if host == "" || final == "" || final == host {
+		return
+	}
+	res.CrossOrigin = final
+	for _, r := range res.Redirects {
+		if redirectHost(r.To) != host {
+			res.CrossOriginVia = r.Type
+			break
+		}
+	}
+}
+
+// redirectHost returns the lower cased host name of rawURL, empty for URLs
+// like about:blank without one.
+func redirectHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
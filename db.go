@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// dbSchemaVersion is stored as the user_version of the database. Bump it and
+// migrate older databases in openResultDB when changing the schema.
+const dbSchemaVersion = 2
+
+const dbSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id      INTEGER PRIMARY KEY,
+	started TEXT NOT NULL,
+	output  TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS captures (
+	id          INTEGER PRIMARY KEY,
+	run_id      INTEGER NOT NULL REFERENCES runs(id),
+	url         TEXT NOT NULL,
+	final_url   TEXT,
+	status      INTEGER,
+	title       TEXT,
+	screenshot  TEXT, -- relative to the output directory of the run
+	sha256      TEXT,
+	phash       TEXT,
+	started     TEXT NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	error       TEXT,
+	tags        TEXT -- comma separated
+);
+CREATE INDEX IF NOT EXISTS captures_url ON captures (url, started);
+`
+
+// resultDB is an SQLite database indexing the captures of every run, so
+// they can be queried across runs. It is safe for concurrent use.
+type resultDB struct {
+	db *sql.DB
+}
+
+func openResultDB(path string) (*resultDB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite only allows a single writer anyway
+	db.SetMaxOpenConns(1)
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	if version > dbSchemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("%s has schema version %d, newer than the supported %d", path, version, dbSchemaVersion)
+	}
+	if _, err := db.Exec(dbSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating tables in %s: %w", path, err)
+	}
+	if version == 1 {
+		if _, err := db.Exec("ALTER TABLE captures ADD COLUMN tags TEXT"); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("migrating %s: %w", path, err)
+		}
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", dbSchemaVersion)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &resultDB{db: db}, nil
+}
+
+// startRun records a new run into output and returns its ID.
+func (d *resultDB) startRun(output string) (int64, error) {
+	if abs, err := filepath.Abs(output); err == nil {
+		output = abs
+	}
+	r, err := d.db.Exec("INSERT INTO runs (started, output) VALUES (?, ?)", time.Now().UTC().Format(time.RFC3339), output)
+	if err != nil {
+		return 0, err
+	}
+	return r.LastInsertId()
+}
+
+// insert records a capture of the run.
+func (d *resultDB) insert(run int64, res *result) error {
+	var hash string
+	if res.Image != nil {
+		// before -annotate and -embed-metadata, so identical pages match
+		hash = hex.EncodeToString(res.ImageHash[:])
+	}
+	_, err := d.db.Exec(`INSERT INTO captures
+		(run_id, url, final_url, status, title, screenshot, sha256, phash, started, duration_ms, error, tags)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run, res.URL, nullString(res.FinalURL), res.Status, nullString(res.Title), nullString(res.Screenshot),
+		nullString(hash), nullString(res.PHash), res.Started.UTC().Format(time.RFC3339Nano), res.DurationMS,
+		nullString(res.Error), nullString(strings.Join(res.Tags, ",")))
+	return err
+}
+
+func (d *resultDB) close() error {
+	return d.db.Close()
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/sha256"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+func TestResultDBHashIgnoresMetadata(t *testing.T) {
+	db, err := openResultDB(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	run, err := db.startRun(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := []byte("the same page")
+	for i, started := range []time.Time{time.Unix(0, 0), time.Unix(3600, 0)} {
+		res := result{Result: screenshot.Result{
+			URL:       "https://example.com",
+			Started:   started,
+			ImageHash: sha256.Sum256(page),
+			// as embedded by -embed-metadata, differing with the time
+			Image: append(append([]byte{}, page...), started.String()...),
+		}}
+		if err := db.insert(run, &res); err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+
+	var hashes int
+	if err := db.db.QueryRow("SELECT COUNT(DISTINCT sha256) FROM captures").Scan(&hashes); err != nil {
+		t.Fatal(err)
+	}
+	if hashes != 1 {
+		t.Errorf("got %d distinct hashes for the same page, want 1", hashes)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// pageDiff is a screenshot that changed, appeared or disappeared between two
+// runs. Paths are relative to the report directory.
+type pageDiff struct {
+	Name     string
+	Old, New string // empty if only in the other run
+	Diff     string
+	Pixels   float64
+	Distance int
+}
+
+var diffTemplate = template.Must(template.New("diff").Funcs(template.FuncMap{
+	"fileURL": fileURL,
+	"percent": func(f float64) string { return fmt.Sprintf("%.2f%%", 100*f) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Screenshot changes</title>
+<style>
+body { font-family: sans-serif; margin: 1em; background: #f4f4f4; }
+.page { background: #fff; border: 1px solid #ddd; padding: .5em; margin-bottom: 1em; overflow-wrap: anywhere; }
+.images { display: grid; grid-template-columns: repeat(3, 1fr); gap: .5em; }
+.images img { width: 100%; border: 1px solid #eee; }
+.score { color: #666; }
+</style>
+</head>
+<body>
+<h1>{{len .}} changed pages</h1>
+{{- range .}}
+<div class="page">
+<div><b>{{.Name}}</b></div>
+{{- if and .Old .New}}
+<div class="score">{{percent .Pixels}} of pixels differ, perceptual distance {{.Distance}}</div>
+{{- else if .New}}
+<div class="score">new page</div>
+{{- else}}
+<div class="score">page gone</div>
+{{- end}}
+<div class="images">
+<div>{{if .Old}}<a href="{{fileURL .Old}}"><img src="{{fileURL .Old}}" loading="lazy"></a>{{end}}</div>
+<div>{{if .New}}<a href="{{fileURL .New}}"><img src="{{fileURL .New}}" loading="lazy"></a>{{end}}</div>
+<div>{{if .Diff}}<a href="{{fileURL .Diff}}"><img src="{{fileURL .Diff}}" loading="lazy"></a>{{end}}</div>
+</div>
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+// runDiff implements the diff subcommand, comparing the screenshots of two
+// output directories by file name.
+func runDiff(args []string) error {
+	fset := flag.NewFlagSet("diff", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: screenshot diff [flags] <old output dir> <new output dir>")
+		fset.PrintDefaults()
+	}
+	var report string
+	fset.StringVar(&report, "o", "diff", "directory to write the report to")
+	var minPixels float64
+	fset.Float64Var(&minPixels, "min-pixels", 0.001, "fraction (0-1) of pixels that must differ for a page to count as changed")
+	var minDistance int
+	fset.IntVar(&minDistance, "min-distance", 0, "perceptual hash distance (0-64) a page must also reach to count as changed")
+	fset.Parse(args)
+	if fset.NArg() != 2 {
+		fset.Usage()
+		os.Exit(2)
+	}
+	oldDir, newDir := fset.Arg(0), fset.Arg(1)
+
+	oldShots, err := listScreenshots(oldDir)
+	if err != nil {
+		return err
+	}
+	newShots, err := listScreenshots(newDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(report, 0755); err != nil {
+		return err
+	}
+
+	rel := func(dir, name string) string {
+		path, err := filepath.Abs(filepath.Join(dir, name))
+		if err != nil {
+			return ""
+		}
+		absReport, _ := filepath.Abs(report)
+		if r, err := filepath.Rel(absReport, path); err == nil {
+			return filepath.ToSlash(r)
+		}
+		return path
+	}
+
+	var diffs []pageDiff
+	for name, oldPath := range oldShots {
+		if _, ok := newShots[name]; !ok {
+			diffs = append(diffs, pageDiff{Name: name, Old: rel(oldDir, oldPath)})
+		}
+	}
+	for name, newPath := range newShots {
+		oldPath, ok := oldShots[name]
+		if !ok {
+			diffs = append(diffs, pageDiff{Name: name, New: rel(newDir, newPath)})
+			continue
+		}
+
+		a, err := os.ReadFile(filepath.Join(oldDir, oldPath))
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(filepath.Join(newDir, newPath))
+		if err != nil {
+			return err
+		}
+		cmp, err := screenshot.Compare(a, b)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "comparing %s: %s\n", name, err)
+			continue
+		}
+		if cmp.Pixels < minPixels || cmp.Distance < minDistance {
+			continue
+		}
+
+		diffName := strings.TrimSuffix(name, filepath.Ext(name)) + ".diff.png"
+		if err := writePNG(filepath.Join(report, diffName), cmp); err != nil {
+			return err
+		}
+		diffs = append(diffs, pageDiff{
+			Name:     name,
+			Old:      rel(oldDir, oldPath),
+			New:      rel(newDir, newPath),
+			Diff:     diffName,
+			Pixels:   cmp.Pixels,
+			Distance: cmp.Distance,
+		})
+	}
+
+	// biggest changes first, then the pages that appeared or disappeared
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Pixels != diffs[j].Pixels {
+			return diffs[i].Pixels > diffs[j].Pixels
+		}
+		return diffs[i].Name < diffs[j].Name
+	})
+
+	f, err := os.Create(filepath.Join(report, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := diffTemplate.Execute(f, diffs); err != nil {
+		return err
+	}
+	fmt.Printf("%d of %d pages changed, report written to %s\n", len(diffs), len(newShots), f.Name())
+	return f.Close()
+}
+
+// listScreenshots returns the screenshots of the pages captured into dir, by
+// their path relative to it, with the path of the file to compare. They are
+// read from results.jsonl, leaving out failed and blank pages, or else found
+// by extension, leaving out thumbnails, favicons and the files of -sizes.
+func listScreenshots(dir string) (map[string]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "results.jsonl")); err == nil {
+		results, err := loadResults(dir)
+		if err != nil {
+			return nil, err
+		}
+		shots := map[string]string{}
+		for _, r := range results {
+			if r.Blank != "" {
+				continue
+			}
+			shots[shotName(r.Screenshot)] = r.Screenshot
+		}
+		return shots, nil
+	}
+
+	shots := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case "thumbs", "blank", "errors":
+				if path != dir {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case screenshot.FormatPNG.Extension(), screenshot.FormatJPEG.Extension(), screenshot.FormatWebP.Extension():
+		default:
+			return nil
+		}
+		base := strings.TrimSuffix(d.Name(), filepath.Ext(path))
+		if strings.HasSuffix(base, ".favicon") || sizeSuffix.MatchString(base) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		shots[filepath.ToSlash(rel)] = filepath.ToSlash(rel)
+		return nil
+	})
+	return shots, err
+}
+
+// sizeSuffix matches the end of the files of -sizes, like _1280x800.
+var sizeSuffix = regexp.MustCompile(`_\d+x\d+$`)
+
+// shotName is the path of a screenshot without the round directory an
+// unchanged one of monitor points back to, like ../20240101T120000/.
+func shotName(path string) string {
+	if strings.HasPrefix(path, "../") {
+		if _, rest, ok := strings.Cut(strings.TrimPrefix(path, "../"), "/"); ok {
+			return rest
+		}
+	}
+	return path
+}
+
+func writePNG(path string, cmp screenshot.Comparison) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := png.Encode(f, cmp.Diff); err != nil {
+		return err
+	}
+	return f.Close()
+}
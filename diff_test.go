@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListScreenshots(t *testing.T) {
+	files := []string{
+		"example.com.png",
+		"example.com.favicon.png",
+		"example.com_1280x800.png",
+		"thumbs/example.com.jpg",
+		"blank/blank.example.com.png",
+		"errors/failed.example.com.png",
+	}
+	want := map[string]string{"example.com.png": "example.com.png"}
+
+	t.Run("by extension", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, files...)
+		shots, err := listScreenshots(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(shots, want) {
+			t.Errorf("listScreenshots() = %v, want %v", shots, want)
+		}
+	})
+
+	t.Run("from results", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, files...)
+		results := `{"url":"https://example.com","screenshot":"example.com.png","size_files":["example.com_1280x800.png"],"thumbnail":"thumbs/example.com.jpg"}
+{"url":"https://blank.example.com","screenshot":"blank/blank.example.com.png","blank":"white"}
+{"url":"https://failed.example.com","screenshot":"errors/failed.example.com.png","error":"status 500"}
+`
+		if err := os.WriteFile(filepath.Join(dir, "results.jsonl"), []byte(results), 0644); err != nil {
+			t.Fatal(err)
+		}
+		shots, err := listScreenshots(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(shots, want) {
+			t.Errorf("listScreenshots() = %v, want %v", shots, want)
+		}
+	})
+}
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// diskPoll is how often the free space is checked again while captures are
+// paused for it.
+const diskPoll = 30 * time.Second
+
+// diskGuard holds captures back while the disk of the output directory has
+// less than min bytes free, so a full disk pauses a run instead of failing
+// everything written. It is safe for concurrent use.
+type diskGuard struct {
+	path   string
+	min    uint64
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	paused bool
+}
+
+// ok reports whether there is enough space free to start a capture.
+func (g *diskGuard) ok() bool {
+	free, err := freeDisk(g.path)
+	if err != nil {
+		// better to carry on than to stall a run for good
+		g.logger.Debug("checking free disk space", "path", g.path, "err", err)
+		return true
+	}
+	enough := free >= g.min
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !enough && !g.paused {
+		g.logger.Warn("disk nearly full, pausing", "path", g.path, "free", humanize.IBytes(free), "min", humanize.IBytes(g.min))
+	} else if enough && g.paused {
+		g.logger.Info("disk space freed, resuming", "path", g.path, "free", humanize.IBytes(free))
+	}
+	g.paused = !enough
+	return enough
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// dryRun checks the URLs read from in, in inputFormat, without capturing
+// them. It writes the output path every URL would be saved to, and reports
+// malformed lines, duplicates and URLs given the same file names, also if
+// only differing in case, after normalizing the URLs if normalize is set.
+// The records of structured input are saved at their name, if they have one.
+// It returns the number of problems found.
+func dryRun(w io.Writer, in io.Reader, inputFormat, output, ext string, namer *screenshot.Namer, normalize bool) (int, error) {
+	// names of the URLs of structured input, like the targets of a run
+	names := map[string]string{}
+	switch inputFormat {
+	case "", inputPlain:
+	case inputJSON, inputCSV:
+		list, err := parseTargets(inputFormat, in)
+		if err != nil {
+			return 0, err
+		}
+		urls := make([]string, len(list))
+		for i, t := range list {
+			urls[i] = t.URL
+			if normalize {
+				urls[i] = normalizeURL(t.URL)
+			}
+			// the last record of a URL wins
+			names[urls[i]] = t.Name
+		}
+		in = strings.NewReader(strings.Join(urls, "\n"))
+	default:
+		urls, err := parseInput(inputFormat, in)
+		if err != nil {
+			return 0, err
+		}
+		in = strings.NewReader(strings.Join(urls, "\n"))
+	}
+
+	problems := 0
+	lines := map[string]int{}    // first line of every URL
+	paths := map[string]string{} // by lower case path, like pathClaims
+	sc := bufio.NewScanner(in)
+	for n := 1; sc.Scan(); n++ {
+		// skipped, and tags after the URL accepted, as in a run
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		requestURL, _ := splitTags(line)
+		if normalize {
+			requestURL = normalizeURL(requestURL)
+		}
+		if err := checkURL(requestURL); err != nil {
+			fmt.Fprintf(w, "line %d: malformed: %v\n", n, err)
+			problems++
+			continue
+		}
+		if first, ok := lines[requestURL]; ok {
+			fmt.Fprintf(w, "line %d: duplicate of line %d: %s\n", n, first, requestURL)
+			problems++
+			continue
+		}
+		lines[requestURL] = n
+
+		probed := !strings.Contains(requestURL, "://")
+		pathURL := requestURL
+		if probed {
+			// the scheme and port that work are not known yet
+			pathURL = "https://" + requestURL
+		}
+		path, err := namer.Filepath(output, &screenshot.Result{URL: pathURL})
+		if err != nil {
+			fmt.Fprintf(w, "line %d: malformed: %v\n", n, err)
+			problems++
+			continue
+		}
+		if name := names[requestURL]; name != "" {
+			path = filepath.Join(output, name)
+		}
+		rel, _ := filepath.Rel(output, path+ext)
+		rel = filepath.ToSlash(rel)
+		if other, ok := paths[strings.ToLower(rel)]; ok {
+			fmt.Fprintf(w, "line %d: %s gets the file name of %s at %s, with a hash of its URL appended\n", n, requestURL, other, rel)
+			problems++
+			continue
+		}
+		paths[strings.ToLower(rel)] = requestURL
+
+		if probed {
+			fmt.Fprintf(w, "line %d: %s -> %s (probed, depends on the scheme and port that work)\n", n, requestURL, rel)
+		} else {
+			fmt.Fprintf(w, "line %d: %s -> %s\n", n, requestURL, rel)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return problems, err
+	}
+	fmt.Fprintf(w, "%d URLs, %d problems\n", len(lines), problems)
+	return problems, nil
+}
+
+// checkURL reports why requestURL cannot be captured, if it cannot.
+func checkURL(requestURL string) error {
+	if strings.TrimSpace(requestURL) == "" {
+		return fmt.Errorf("empty line")
+	}
+	if strings.TrimSpace(requestURL) != requestURL {
+		return fmt.Errorf("%q has leading or trailing spaces", requestURL)
+	}
+	raw := requestURL
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Hostname() == "" && u.Scheme != "file" {
+		return fmt.Errorf("%q has no host", requestURL)
+	}
+	return nil
+}
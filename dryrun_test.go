@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDryRunSkipsCommentsAndBlankLines(t *testing.T) {
+	in := `# targets of client a
+https://example.com client-a,web
+
+https://example.org
+`
+	var out bytes.Buffer
+	problems, err := dryRun(&out, strings.NewReader(in), inputPlain, "out", ".png", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems != 0 {
+		t.Errorf("dryRun() found %d problems, want 0:\n%s", problems, &out)
+	}
+	if !strings.HasSuffix(out.String(), "2 URLs, 0 problems\n") {
+		t.Errorf("dryRun() output ends in %q, want 2 URLs", out.String())
+	}
+}
+
+func TestDryRunUsesTargetNames(t *testing.T) {
+	in := `{"url": "https://example.com/login", "name": "client-a/login"}
+{"url": "https://example.org"}
+`
+	var out bytes.Buffer
+	if _, err := dryRun(&out, strings.NewReader(in), inputJSON, "out", ".png", nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := "line 1: https://example.com/login -> client-a/login.png\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("dryRun() output\n%s\ndoes not contain %q", &out, want)
+	}
+}
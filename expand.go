@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sources of -expand.
+const (
+	expandSitemap = "sitemap"
+	expandRobots  = "robots"
+)
+
+const (
+	// expandMaxSitemaps is how many sitemaps of a site are read at most,
+	// following sitemap indexes.
+	expandMaxSitemaps = 20
+	// expandMaxBody is the most read of a sitemap or robots.txt.
+	expandMaxBody = 50 << 20
+)
+
+// expander finds more pages of the sites of the input in their sitemaps and
+// robots.txt, at most perHost of every host. Every site is only looked at
+// once. It is safe for concurrent use.
+type expander struct {
+	client  *http.Client
+	sitemap bool
+	robots  bool
+	perHost int // 0 for no limit
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	origins map[string]bool
+	found   map[string]bool
+}
+
+// parseExpand checks the sources given to -expand.
+func parseExpand(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var sources []string
+	for _, s := range strings.Split(raw, ",") {
+		s = strings.TrimSpace(s)
+		switch s {
+		case expandSitemap, expandRobots:
+		default:
+			return nil, fmt.Errorf("unknown -expand source %q, must be sitemap or robots", s)
+		}
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+// newExpander returns an expander reading sources, fetched through proxy if
+// set like Chrome would.
+func newExpander(sources []string, perHost int, proxy string, logger *slog.Logger) (*expander, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// like Chrome, which is started ignoring certificate errors
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	e := &expander{
+		client:  &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		perHost: perHost,
+		logger:  logger,
+		origins: map[string]bool{},
+		found:   map[string]bool{},
+	}
+	for _, s := range sources {
+		switch s {
+		case expandSitemap:
+			e.sitemap = true
+		case expandRobots:
+			e.robots = true
+		}
+	}
+	return e, nil
+}
+
+// expand returns the pages found for the site of res, if it is a page of the
+// input whose site was not expanded yet.
+func (e *expander) expand(ctx context.Context, res *result) []string {
+	pageURL := res.FinalURL
+	if pageURL == "" {
+		pageURL = res.URL
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	origin := u.Scheme + "://" + u.Host
+	e.mu.Lock()
+	if e.found[res.URL] || e.origins[origin] {
+		e.mu.Unlock()
+		return nil
+	}
+	e.origins[origin] = true
+	e.mu.Unlock()
+
+	var sitemaps, pages []string
+	if e.sitemap {
+		sitemaps = append(sitemaps, origin+"/sitemap.xml")
+	}
+	if e.robots {
+		more, allowed := e.readRobots(ctx, origin)
+		sitemaps = append(sitemaps, more...)
+		pages = append(pages, allowed...)
+	}
+	pages = append(pages, e.readSitemaps(ctx, sitemaps)...)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var next []string
+	for _, p := range pages {
+		pu, err := url.Parse(p)
+		if err != nil || !strings.EqualFold(pu.Hostname(), u.Hostname()) || (pu.Scheme != "http" && pu.Scheme != "https") {
+			// other sites are not what was asked for
+			continue
+		}
+		pu.Fragment = ""
+		p = pu.String()
+		if e.found[p] || p == res.URL || p == res.FinalURL {
+			continue
+		}
+		if e.perHost > 0 && len(next) >= e.perHost {
+			break
+		}
+		e.found[p] = true
+		next = append(next, p)
+	}
+	if len(next) > 0 {
+		e.logger.Debug("expanded", "url", origin, "pages", len(next))
+	}
+	return next
+}
+
+// readRobots returns the sitemaps listed in the robots.txt of origin and the
+// pages its Allow and Disallow rules name. Rules with wildcards are left out.
+func (e *expander) readRobots(ctx context.Context, origin string) (sitemaps, pages []string) {
+	body, err := e.get(ctx, origin+"/robots.txt")
+	if err != nil {
+		e.logger.Debug("reading robots.txt", "url", origin, "err", err)
+		return nil, nil
+	}
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "sitemap":
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		case "allow", "disallow":
+			if strings.HasPrefix(value, "/") && value != "/" && !strings.ContainsAny(value, "*$") {
+				pages = append(pages, origin+value)
+			}
+		}
+	}
+	return sitemaps, pages
+}
+
+// sitemapXML is either a sitemap or a sitemap index.
+type sitemapXML struct {
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// readSitemaps returns the pages listed in sitemaps and the sitemaps they
+// index in turn.
+func (e *expander) readSitemaps(ctx context.Context, sitemaps []string) []string {
+	var pages []string
+	seen := map[string]bool{}
+	for read := 0; len(sitemaps) > 0 && read < expandMaxSitemaps; {
+		sitemapURL := sitemaps[0]
+		sitemaps = sitemaps[1:]
+		if seen[sitemapURL] {
+			continue
+		}
+		seen[sitemapURL] = true
+		read++
+
+		body, err := e.get(ctx, sitemapURL)
+		if err != nil {
+			e.logger.Debug("reading sitemap", "url", sitemapURL, "err", err)
+			continue
+		}
+		var sm sitemapXML
+		if err := xml.Unmarshal(body, &sm); err != nil {
+			e.logger.Debug("reading sitemap", "url", sitemapURL, "err", err)
+			continue
+		}
+		for _, loc := range sm.URLs {
+			pages = append(pages, strings.TrimSpace(loc.Loc))
+		}
+		for _, loc := range sm.Sitemaps {
+			sitemaps = append(sitemaps, strings.TrimSpace(loc.Loc))
+		}
+	}
+	return pages
+}
+
+// get fetches rawURL, decompressing gzipped sitemaps.
+func (e *expander) get(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, expandMaxBody))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(io.LimitReader(zr, expandMaxBody))
+	}
+	return body, nil
+}
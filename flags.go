@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+	"gopkg.in/yaml.v3"
+)
+
+// stringList is a flag that can be given multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// flagSet reports whether the flag name was given on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// parseHeaders parses "Name: value" pairs as given to -header.
+func parseHeaders(raw []string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, h := range raw {
+		name, value, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, must be \"Name: value\"", h)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// readHeaderFile reads the "Name: value" lines of file, as given to
+// -header-file, skipping empty ones and # comments.
+func readHeaderFile(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var raw []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			raw = append(raw, line)
+		}
+	}
+	return raw, nil
+}
+
+// headerTemplates moves the headers with placeholders like {{ip}} out of
+// headers, returning them as the templates filled in for every request.
+func headerTemplates(headers map[string]string) map[string]string {
+	var templates map[string]string
+	for name, value := range headers {
+		if !strings.Contains(value, "{{") {
+			continue
+		}
+		if templates == nil {
+			templates = map[string]string{}
+		}
+		templates[name] = value
+		delete(headers, name)
+	}
+	return templates
+}
+
+// parseCookies parses cookies as given to -cookie, using the same syntax as
+// a Cookie request header.
+func parseCookies(raw []string) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	for _, c := range raw {
+		parsed, err := http.ParseCookie(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie %q: %w", c, err)
+		}
+		cookies = append(cookies, parsed...)
+	}
+	return cookies, nil
+}
+
+// splitList splits a comma separated flag value, trimming spaces and leaving
+// out empty items.
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseGeo parses a -geo value like "52.52,13.405".
+func parseGeo(raw string) (*screenshot.Geolocation, error) {
+	lat, lon, ok := strings.Cut(raw, ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid geolocation %q, must be \"lat,lon\"", raw)
+	}
+	var geo screenshot.Geolocation
+	var err error
+	if geo.Latitude, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil || geo.Latitude < -90 || geo.Latitude > 90 {
+		return nil, fmt.Errorf("invalid latitude in %q", raw)
+	}
+	if geo.Longitude, err = strconv.ParseFloat(strings.TrimSpace(lon), 64); err != nil || geo.Longitude < -180 || geo.Longitude > 180 {
+		return nil, fmt.Errorf("invalid longitude in %q", raw)
+	}
+	return &geo, nil
+}
+
+// parseSizes parses a -sizes value like "1920x1080,375x812".
+func parseSizes(raw string) ([]screenshot.Size, error) {
+	var sizes []screenshot.Size
+	for _, item := range splitList(raw) {
+		w, h, ok := strings.Cut(item, "x")
+		width, werr := strconv.ParseInt(w, 10, 64)
+		height, herr := strconv.ParseInt(h, 10, 64)
+		if !ok || werr != nil || herr != nil || width <= 0 || height <= 0 {
+			return nil, fmt.Errorf("invalid size %q, must be \"widthxheight\"", item)
+		}
+		sizes = append(sizes, screenshot.Size{Width: width, Height: height})
+	}
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no sizes in %q", raw)
+	}
+	return sizes, nil
+}
+
+// parseCrop parses a -crop value like "1280x720+0+100", the width and
+// height of the part to keep and its offset from the top left corner.
+func parseCrop(raw string) (image.Rectangle, error) {
+	var w, h, x, y int
+	var rest string
+	n, _ := fmt.Sscanf(raw, "%dx%d+%d+%d%s", &w, &h, &x, &y, &rest)
+	if n != 4 || w <= 0 || h <= 0 || x < 0 || y < 0 {
+		return image.Rectangle{}, fmt.Errorf("invalid crop %q, must be \"widthxheight+x+y\"", raw)
+	}
+	return image.Rect(x, y, x+w, y+h), nil
+}
+
+// parseThrottle parses a -throttle value, 3g, slow-3g or
+// "custom:down,up,latency" with the speeds in kbit/s and the latency in
+// milliseconds.
+func parseThrottle(raw string) (*screenshot.Throttle, error) {
+	switch raw {
+	case "3g":
+		t := screenshot.Throttle3G
+		return &t, nil
+	case "slow-3g":
+		t := screenshot.ThrottleSlow3G
+		return &t, nil
+	}
+	custom, ok := strings.CutPrefix(raw, "custom:")
+	parts := strings.Split(custom, ",")
+	if !ok || len(parts) != 3 {
+		return nil, fmt.Errorf("invalid throttle %q, must be 3g, slow-3g or custom:down,up,latency", raw)
+	}
+	var values [3]int64
+	for i, p := range parts {
+		v, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid throttle %q, must be 3g, slow-3g or custom:down,up,latency", raw)
+		}
+		values[i] = v
+	}
+	return &screenshot.Throttle{
+		Download: values[0] * 1000 / 8,
+		Upload:   values[1] * 1000 / 8,
+		Latency:  time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+// parseChromeFlags turns -chrome-flag values like "no-sandbox" or
+// "--proxy-bypass-list=<-loopback>" into flags for the allocator.
+func parseChromeFlags(raw []string) map[string]interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	flags := make(map[string]interface{}, len(raw))
+	for _, f := range raw {
+		name, value, ok := strings.Cut(strings.TrimLeft(f, "-"), "=")
+		if ok {
+			flags[name] = value
+		} else {
+			flags[name] = true
+		}
+	}
+	return flags
+}
+
+// loadScript reads the -script file, a YAML list of steps like
+//
+//   - action: navigate
+//     url: https://app.example.com/login
+//   - action: type
+//     selector: "#username"
+//     text: admin
+//   - action: click
+//     selector: button[type=submit]
+//   - action: wait
+//     selector: "#dashboard"
+func loadScript(path string) ([]screenshot.Step, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var steps []screenshot.Step
+	if err := yaml.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("reading script %s: %w", path, err)
+	}
+	return steps, nil
+}
+
+// loadCookieJar reads a JSON array of cookies, in the format of the DevTools
+// protocol, from path.
+func loadCookieJar(path string) ([]screenshot.Cookie, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cookies []screenshot.Cookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return nil, fmt.Errorf("reading cookie jar %s: %w", path, err)
+	}
+	return cookies, nil
+}
+
+// parseAuth parses the -auth credentials, as user:pass, and the lines of the
+// -auth-file, if given, which are like "example.com user:pass". Empty lines
+// and lines starting with # are skipped.
+func parseAuth(raw, file string) (*url.Userinfo, map[string]*url.Userinfo, error) {
+	var creds *url.Userinfo
+	if raw != "" {
+		user, pass, ok := strings.Cut(raw, ":")
+		if !ok || user == "" {
+			return nil, nil, fmt.Errorf("invalid -auth, must be user:pass")
+		}
+		creds = url.UserPassword(user, pass)
+	}
+	if file == "" {
+		return creds, nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	hosts := map[string]*url.Userinfo{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		host, userpass, _ := strings.Cut(line, " ")
+		user, pass, ok := strings.Cut(strings.TrimSpace(userpass), ":")
+		if !ok || host == "" || user == "" {
+			return nil, nil, fmt.Errorf("%s:%d: invalid credentials, must be like \"example.com user:pass\"", file, i+1)
+		}
+		hosts[strings.ToLower(host)] = url.UserPassword(user, pass)
+	}
+	return creds, hosts, nil
+}
+
+// parseResolve parses -resolve mappings like "example.com:10.0.0.1" and those
+// in the lines of the -resolve-file, if given. Empty lines and lines starting
+// with # are skipped.
+func parseResolve(raw []string, file string) (map[string]string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				raw = append(raw, line)
+			}
+		}
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	resolve := make(map[string]string, len(raw))
+	for _, r := range raw {
+		host, ip, ok := strings.Cut(r, ":")
+		if !ok || host == "" || net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid resolve %q, must be like example.com:10.0.0.1", r)
+		}
+		resolve[host] = ip
+	}
+	return resolve, nil
+}
+
+// parsePolicies parses -on-error policies like "dns=skip", by error category.
+// Unknown categories and policies are left to screenshot.New.
+func parsePolicies(raw []string) (map[string]screenshot.Policy, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	policies := make(map[string]screenshot.Policy, len(raw))
+	for _, r := range raw {
+		category, policy, ok := strings.Cut(r, "=")
+		if !ok || category == "" {
+			return nil, fmt.Errorf("invalid -on-error %q, must be like dns=skip", r)
+		}
+		policies[strings.TrimSpace(category)] = screenshot.Policy(strings.TrimSpace(policy))
+	}
+	return policies, nil
+}
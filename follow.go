@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// followPoll is how often a followed input is checked for more once it
+// reached its end.
+const followPoll = time.Second
+
+// followReader reads r like tail -f, waiting for more where r ends, until ctx
+// is done. It ends at the end of r then.
+type followReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (f *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		if n > 0 || !errors.Is(err, io.EOF) {
+			return n, err
+		}
+		select {
+		case <-f.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(followPoll):
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+var galleryTemplate = template.Must(template.New("gallery").Funcs(template.FuncMap{
+	"fileURL":    fileURL,
+	"humanBytes": func(n int64) string { return humanize.Bytes(uint64(n)) },
+	"join":       strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Screenshots</title>
+<style>
+body { font-family: sans-serif; margin: 1em; background: #f4f4f4; }
+#filter { width: 100%; padding: .5em; font-size: 1.1em; box-sizing: border-box; margin-bottom: 1em; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(320px, 1fr)); gap: 1em; }
+.card { background: #fff; border: 1px solid #ddd; padding: .5em; overflow-wrap: anywhere; }
+.card img { width: 100%; border: 1px solid #eee; }
+.status { font-weight: bold; }
+.error { color: #b00; }
+.cluster { color: #666; }
+.ip { color: #666; }
+.perf { color: #666; }
+.security { color: #666; }
+.variant { color: #666; }
+.differs { color: #b60; font-weight: bold; }
+.slow { display: inline-block; background: #f93; color: #000; padding: 0 .3em; }
+.cross-origin { display: inline-block; background: #f66; color: #000; padding: 0 .3em; }
+.page-type { display: inline-block; background: #fd3; padding: 0 .3em; }
+.tag { display: inline-block; background: #def; padding: 0 .3em; margin-right: .3em; cursor: pointer; }
+</style>
+</head>
+<body>
+<input id="filter" type="search" placeholder="Filter by URL, title, status, server, IP, AS, tag or error" autofocus>
+<div class="grid">
+{{- range .}}
+<div class="card">
+{{- if .Screenshot}}
+<a href="{{fileURL .Screenshot}}"><img src="{{fileURL (or .Thumbnail .Screenshot)}}" loading="lazy"></a>
+{{- end}}
+<div><a href="{{.URL}}">{{.URL}}</a></div>
+{{- if .Tags}}
+<div>{{range .Tags}}<span class="tag">{{.}}</span>{{end}}</div>
+{{- end}}
+{{- if .Status}}
+<div><span class="status">{{.Status}}</span> {{.ContentType}} {{.Server}}</div>
+{{- end}}
+{{- if .IPs}}
+<div class="ip">{{index .IPs 0}}{{if .ASN}} AS{{.ASN}} {{.ASOrg}}{{end}}</div>
+{{- end}}
+{{- if .CrossOrigin}}
+<div><span class="cross-origin">redirects to {{.CrossOrigin}}</span> {{.CrossOriginVia}}</div>
+{{- end}}
+<div>{{.Title}}</div>
+{{- if .PageType}}
+<div class="page-type">{{.PageType}}</div>
+{{- end}}
+{{- if .IPVariant}}
+<div class="variant{{if .Differs}} differs{{end}}">{{if eq .IPVariant "host"}}IP with Host header{{else}}bare IP{{end}} of {{.VariantOf}}{{if .Differs}}, differs in {{join .Differs ", "}}{{end}}</div>
+{{- end}}
+{{- if .Security}}
+<div class="security" title="{{range .Security.Issues}}{{.}}&#10;{{end}}">security {{.Security.Score}}/100</div>
+{{- end}}
+{{- if .Performance}}
+<div class="perf">{{if .Slow}}<span class="slow">slow</span> {{end}}load {{.Performance.Load}} ms, {{.Performance.DOMNodes}} nodes, {{humanBytes .Performance.TransferSize}}</div>
+{{- end}}
+{{- if .Cluster}}
+<div class="cluster">cluster {{.Cluster}}</div>
+{{- end}}
+{{- if .Error}}
+<div class="error">{{.Error}}</div>
+{{- end}}
+</div>
+{{- end}}
+</div>
+<script>
+var filter = document.getElementById("filter");
+filter.addEventListener("input", function() {
+	var q = this.value.toLowerCase();
+	document.querySelectorAll(".card").forEach(function(card) {
+		card.style.display = card.textContent.toLowerCase().includes(q) ? "" : "none";
+	});
+});
+// clicking a tag shows only the pages with it
+document.querySelectorAll(".tag").forEach(function(tag) {
+	tag.addEventListener("click", function() {
+		filter.value = tag.textContent;
+		filter.dispatchEvent(new Event("input"));
+	});
+});
+</script>
+</body>
+</html>
+`))
+
+// fileURL turns a relative file path into a relative URL. Screenshot names
+// can contain literal '%' characters which would otherwise be decoded by the
+// browser.
+func fileURL(path string) string {
+	u := url.URL{Path: filepath.ToSlash(path)}
+	return u.EscapedPath()
+}
+
+// writeGallery writes index.html to the output directory listing every
+// captured URL, sorted by cluster and URL.
+func writeGallery(output string, results []result) error {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Cluster != results[j].Cluster {
+			return results[i].Cluster < results[j].Cluster
+		}
+		return results[i].URL < results[j].URL
+	})
+
+	f, err := os.Create(filepath.Join(output, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return galleryTemplate.Execute(f, results)
+}
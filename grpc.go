@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// grpcService serves the Screenshot service of proto/screenshot.proto with
+// the browser of a server.
+type grpcService struct {
+	*server
+}
+
+// screenshotService is implemented by the handler of grpcServiceDesc.
+type screenshotService interface {
+	captureOne(ctx context.Context, req *captureRequest) (*captureResponse, error)
+	captureStream(stream grpc.ServerStream) error
+}
+
+var grpcServiceDesc = grpc.ServiceDesc{
+	ServiceName: "screenshot.Screenshot",
+	HandlerType: (*screenshotService)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Capture",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(captureRequest)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handle := func(ctx context.Context, req any) (any, error) {
+				return srv.(screenshotService).captureOne(ctx, req.(*captureRequest))
+			}
+			if interceptor == nil {
+				return handle(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/screenshot.Screenshot/Capture"}
+			return interceptor(ctx, req, info, handle)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName: "CaptureStream",
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			return srv.(screenshotService).captureStream(stream)
+		},
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+	Metadata: "proto/screenshot.proto",
+}
+
+// newGRPCServer returns a gRPC server of the Screenshot service using s.
+func newGRPCServer(s *server) *grpc.Server {
+	gs := grpc.NewServer(grpc.ForceServerCodec(grpcCodec{}))
+	gs.RegisterService(&grpcServiceDesc, &grpcService{s})
+	return gs
+}
+
+func (g *grpcService) captureOne(ctx context.Context, req *captureRequest) (*captureResponse, error) {
+	if req.URL == "" {
+		return nil, status.Error(codes.InvalidArgument, "no url")
+	}
+	if !g.acquire(ctx) {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	defer g.release()
+	return g.respond(ctx, req), nil
+}
+
+// captureStream captures the URLs of the requests as they arrive. There are
+// no more requests read than captures may run, so a client sending faster
+// than they finish is held back.
+func (g *grpcService) captureStream(stream grpc.ServerStream) error {
+	ctx := stream.Context()
+	var sendMu sync.Mutex
+	var wg sync.WaitGroup
+	// responses must not be sent once this returns
+	defer wg.Wait()
+	for {
+		req := new(captureRequest)
+		if err := stream.RecvMsg(req); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !g.acquire(ctx) {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer g.release()
+			var resp *captureResponse
+			if req.URL == "" {
+				resp = &captureResponse{ID: req.ID, Error: "no url"}
+			} else {
+				resp = g.respond(ctx, req)
+			}
+			sendMu.Lock()
+			defer sendMu.Unlock()
+			if err := stream.SendMsg(resp); err != nil {
+				g.log.Debug("sending response", "url", req.URL, "err", err)
+			}
+		}()
+	}
+}
+
+// respond captures the URL of req. Failed captures are answered with the
+// error in the response rather than failing the call, so that a stream
+// carries on.
+func (g *grpcService) respond(ctx context.Context, req *captureRequest) *captureResponse {
+	res, _ := g.capture(ctx, req.URL, req.Save)
+	resp := &captureResponse{
+		ID:         req.ID,
+		URL:        res.URL,
+		FinalURL:   res.FinalURL,
+		Status:     res.Status,
+		Title:      res.Title,
+		Image:      res.Image,
+		Screenshot: res.Screenshot,
+		Error:      res.Error,
+	}
+	if len(res.Image) > 0 {
+		resp.ImageType = http.DetectContentType(res.Image)
+	}
+	if line, err := json.Marshal(res); err == nil {
+		resp.Result = string(line)
+	}
+	return resp
+}
+
+// captureRequest and captureResponse are the messages of
+// proto/screenshot.proto. They are encoded by hand with protowire, so that
+// the service needs no generated code.
+type captureRequest struct {
+	ID   string
+	URL  string
+	Save bool
+}
+
+type captureResponse struct {
+	ID         string
+	URL        string
+	FinalURL   string
+	Status     int64
+	Title      string
+	Image      []byte
+	ImageType  string
+	Result     string
+	Screenshot string
+	Error      string
+}
+
+func (r *captureRequest) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return consumeString(b, &r.ID)
+		case num == 2 && typ == protowire.BytesType:
+			return consumeString(b, &r.URL)
+		case num == 3 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			r.Save = v != 0
+			return n
+		}
+		return protowire.ConsumeFieldValue(num, typ, b)
+	})
+}
+
+func (r *captureRequest) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, r.ID)
+	b = appendString(b, 2, r.URL)
+	if r.Save {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	return b
+}
+
+func (r *captureResponse) unmarshal(b []byte) error {
+	stringFields := map[protowire.Number]*string{
+		1: &r.ID, 2: &r.URL, 3: &r.FinalURL, 5: &r.Title, 7: &r.ImageType,
+		8: &r.Result, 9: &r.Screenshot, 10: &r.Error,
+	}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		switch {
+		case num == 4 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			r.Status = int64(v)
+			return n
+		case num == 6 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			r.Image = append([]byte(nil), v...)
+			return n
+		case stringFields[num] != nil && typ == protowire.BytesType:
+			return consumeString(b, stringFields[num])
+		}
+		return protowire.ConsumeFieldValue(num, typ, b)
+	})
+}
+
+func (r *captureResponse) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, r.ID)
+	b = appendString(b, 2, r.URL)
+	b = appendString(b, 3, r.FinalURL)
+	if r.Status != 0 {
+		b = protowire.AppendTag(b, 4, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(r.Status))
+	}
+	b = appendString(b, 5, r.Title)
+	if len(r.Image) > 0 {
+		b = protowire.AppendTag(b, 6, protowire.BytesType)
+		b = protowire.AppendBytes(b, r.Image)
+	}
+	b = appendString(b, 7, r.ImageType)
+	b = appendString(b, 8, r.Result)
+	b = appendString(b, 9, r.Screenshot)
+	b = appendString(b, 10, r.Error)
+	return b
+}
+
+// consumeFields calls field for every field of the message b, which returns
+// how much of its value it consumed, or a negative protowire error.
+func consumeFields(b []byte, field func(protowire.Number, protowire.Type, []byte) int) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if n = field(num, typ, b); n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func consumeString(b []byte, s *string) int {
+	v, n := protowire.ConsumeString(b)
+	*s = v
+	return n
+}
+
+// appendString appends a string field, which proto3 leaves out if empty.
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// grpcCodec encodes the hand written messages on the wire like protobuf.
+type grpcCodec struct{}
+
+type grpcMessage interface {
+	marshal() []byte
+	unmarshal([]byte) error
+}
+
+func (grpcCodec) Name() string {
+	return "proto"
+}
+
+func (grpcCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(grpcMessage)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+	return m.marshal(), nil
+}
+
+func (grpcCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(grpcMessage)
+	if !ok {
+		return fmt.Errorf("cannot decode into %T", v)
+	}
+	return m.unmarshal(data)
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// Input formats for -input-format.
+const (
+	inputPlain   = "plain"
+	inputNmapXML = "nmap-xml"
+	inputMasscan = "masscan"
+	inputHttpx   = "httpx"
+)
+
+// parseInput reads the URLs to capture from scanner output in format. Ports
+// with a service not known to be http or https are given as host:port, which
+// Capture probes the schemes of.
+func parseInput(format string, r io.Reader) ([]string, error) {
+	switch format {
+	case inputNmapXML:
+		return parseNmapXML(r)
+	case inputMasscan:
+		return parseMasscan(r)
+	case inputHttpx:
+		return parseHttpx(r)
+	case inputJSON, inputCSV:
+		targets, err := parseTargets(format, r)
+		if err != nil {
+			return nil, err
+		}
+		urls := make([]string, len(targets))
+		for i, t := range targets {
+			urls[i] = t.URL
+		}
+		return urls, nil
+	default:
+		return nil, fmt.Errorf("unknown input format %q, must be one of plain, nmap-xml, masscan, httpx, json or csv", format)
+	}
+}
+
+// webPortSchemes are the schemes of the usual web ports, used when the
+// scanner does not say which service is listening.
+var webPortSchemes = map[int]string{
+	80:   "http",
+	443:  "https",
+	8000: "http",
+	8008: "http",
+	8080: "http",
+	8443: "https",
+	8888: "http",
+	4443: "https",
+	9443: "https",
+}
+
+// portURL returns the URL to capture for an open port. scheme may be empty
+// if unknown.
+func portURL(scheme, host string, port int) string {
+	if scheme == "" {
+		scheme = webPortSchemes[port]
+	}
+	hostPort := net.JoinHostPort(host, strconv.Itoa(port))
+	switch {
+	case scheme == "":
+		return hostPort
+	case scheme == "http" && port == 80, scheme == "https" && port == 443:
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		return scheme + "://" + host
+	}
+	return scheme + "://" + hostPort
+}
+
+type nmapRun struct {
+	Hosts []struct {
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"hostnames>hostname"`
+		Ports []struct {
+			Protocol string `xml:"protocol,attr"`
+			PortID   int    `xml:"portid,attr"`
+			State    struct {
+				State string `xml:"state,attr"`
+			} `xml:"state"`
+			Service struct {
+				Name   string `xml:"name,attr"`
+				Tunnel string `xml:"tunnel,attr"`
+			} `xml:"service"`
+		} `xml:"ports>port"`
+	} `xml:"host"`
+}
+
+// parseNmapXML reads nmap -oX output. Every open TCP port running http or
+// https, or where nmap did not detect a service, becomes a URL. Hosts are
+// named after the hostname given on the command line if there was one.
+func parseNmapXML(r io.Reader) ([]string, error) {
+	var run nmapRun
+	if err := xml.NewDecoder(r).Decode(&run); err != nil {
+		return nil, fmt.Errorf("reading nmap xml: %w", err)
+	}
+
+	var urls []string
+	for _, h := range run.Hosts {
+		var host string
+		for _, hn := range h.Hostnames {
+			if hn.Type == "user" {
+				host = hn.Name
+			}
+		}
+		for _, a := range h.Addresses {
+			if host == "" && (a.AddrType == "ipv4" || a.AddrType == "ipv6") {
+				host = a.Addr
+			}
+		}
+		if host == "" {
+			continue
+		}
+
+		for _, p := range h.Ports {
+			if p.Protocol != "tcp" || p.State.State != "open" {
+				continue
+			}
+			name := p.Service.Name
+			var scheme string
+			switch {
+			case name == "https" || strings.HasPrefix(name, "http") && p.Service.Tunnel == "ssl":
+				scheme = "https"
+			case strings.HasPrefix(name, "http"):
+				scheme = "http"
+			case name == "" || name == "unknown":
+			default:
+				continue
+			}
+			urls = append(urls, portURL(scheme, host, p.PortID))
+		}
+	}
+	return urls, nil
+}
+
+// parseMasscan reads masscan -oL or -oJ output.
+func parseMasscan(r io.Reader) ([]string, error) {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err != nil {
+		return nil, err
+	}
+	if first == '[' || first == '{' {
+		return parseMasscanJSON(br)
+	}
+
+	var urls []string
+	sc := bufio.NewScanner(br)
+	for sc.Scan() {
+		// open tcp 80 192.0.2.1 1609523842
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 || fields[0] != "open" || fields[1] != "tcp" {
+			continue
+		}
+		port, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		urls = append(urls, portURL("", fields[3], port))
+	}
+	return urls, sc.Err()
+}
+
+// parseMasscanJSON reads masscan -oJ output. Older versions of masscan
+// write invalid JSON, so every line is decoded on its own.
+func parseMasscanJSON(r io.Reader) ([]string, error) {
+	var urls []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		line = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+		line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+		if line == "" {
+			continue
+		}
+		var rec struct {
+			IP    string `json:"ip"`
+			Ports []struct {
+				Port   int    `json:"port"`
+				Proto  string `json:"proto"`
+				Status string `json:"status"`
+			} `json:"ports"`
+		}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("reading masscan json: %w", err)
+		}
+		for _, p := range rec.Ports {
+			if p.Proto == "tcp" && (p.Status == "" || p.Status == "open") {
+				urls = append(urls, portURL("", rec.IP, p.Port))
+			}
+		}
+	}
+	return urls, sc.Err()
+}
+
+// parseHttpx reads httpx -json output.
+func parseHttpx(r io.Reader) ([]string, error) {
+	var urls []string
+	dec := json.NewDecoder(r)
+	for {
+		var rec struct {
+			URL   string `json:"url"`
+			Input string `json:"input"`
+		}
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			return urls, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading httpx json: %w", err)
+		}
+		if rec.URL != "" {
+			urls = append(urls, rec.URL)
+		} else if rec.Input != "" {
+			urls = append(urls, rec.Input)
+		}
+	}
+}
+
+// peekNonSpace returns the first non whitespace byte of br without consuming
+// it, or 0 if there is none.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if b[0] != ' ' && b[0] != '\t' && b[0] != '\r' && b[0] != '\n' {
+			return b[0], nil
+		}
+		br.ReadByte()
+	}
+}
+
+// defaultPorts are the ports normalizeURL leaves out of URLs.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// trackingParams are query parameters that only track where a visitor came
+// from, removed by normalizeURL. Those ending in _ are prefixes.
+var trackingParams = []string{"utm_", "gclid", "gclsrc", "dclid", "fbclid", "msclkid", "yclid", "mc_cid", "mc_eid", "_ga", "_gl", "igshid", "ref_src"}
+
+// normalizeURL returns requestURL in a canonical form for -normalize, so the
+// same page given in different ways is captured once: the scheme and host
+// lower cased, default ports removed, tracking parameters dropped and the
+// rest of the query sorted. Input that does not parse is returned as is.
+func normalizeURL(requestURL string) string {
+	raw := strings.TrimSpace(requestURL)
+	bare := !strings.Contains(raw, "://")
+	if bare {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return requestURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && (bare || port != defaultPorts[u.Scheme]) {
+		// a bare host's port decides which schemes are probed
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			if isTrackingParam(name) {
+				query.Del(name)
+			}
+		}
+		// Encode sorts by name
+		u.RawQuery = query.Encode()
+	}
+	if u.Path == "" && !bare {
+		u.Path = "/"
+	}
+	if bare {
+		return strings.TrimPrefix(u.String(), "//")
+	}
+	return u.String()
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range trackingParams {
+		if name == p || (strings.HasSuffix(p, "_") && strings.HasPrefix(name, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// findHTMLFiles returns the file:// URLs of the .html and .htm files under
+// dir, in lexical order.
+func findHTMLFiles(dir string) ([]string, error) {
+	var urls []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".html", ".htm":
+			if d.Type().IsRegular() {
+				fileURL, err := screenshot.FileURL(path)
+				if err != nil {
+					return err
+				}
+				urls = append(urls, fileURL)
+			}
+		}
+		return nil
+	})
+	return urls, err
+}
+
+const (
+	// inputBuffer is how many lines of the input are read ahead of the
+	// scheduler, which looks ahead for schedulerLookahead URLs on top, so
+	// huge inputs are read as the captures go rather than all at once.
+	inputBuffer = 1024
+	// maxInputLine is the longest line of input in bytes, longer ones are
+	// skipped.
+	maxInputLine = 64 << 10
+)
+
+// inputReader reads the lines of the input of a run on lines, which is
+// closed once it ended.
+type inputReader struct {
+	lines chan string
+	// tooLong counts the lines skipped for being longer than maxInputLine
+	tooLong atomic.Int64
+	// err is why the input ended, if not at its end, set before done is
+	// closed
+	err  error
+	done chan struct{}
+}
+
+func readInput(in io.Reader, stats *metrics, prog *progress) *inputReader {
+	r := &inputReader{lines: make(chan string, inputBuffer), done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		defer close(r.lines)
+		br := bufio.NewReaderSize(in, maxInputLine)
+		for {
+			line, err := br.ReadSlice('\n')
+			if errors.Is(err, bufio.ErrBufferFull) {
+				stats.inputLine("too_long")
+				prog.skip()
+				r.tooLong.Add(1)
+				// drop the rest of it
+				for errors.Is(err, bufio.ErrBufferFull) {
+					_, err = br.ReadSlice('\n')
+				}
+				if err == nil {
+					continue
+				}
+				line = nil
+			}
+			if len(line) > 0 {
+				stats.inputLine("read")
+				r.lines <- strings.TrimRight(string(line), "\r\n")
+			}
+			if err != nil {
+				if err != io.EOF {
+					r.err = err
+				}
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// failed returns why the input could not be read to its end, nil while it
+// still is.
+func (r *inputReader) failed() error {
+	select {
+	case <-r.done:
+		return r.err
+	default:
+		return nil
+	}
+}
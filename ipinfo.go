@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// ipLookupTimeout is how long resolving the addresses of a host may take.
+const ipLookupTimeout = 5 * time.Second
+
+// ipInfo adds the IP addresses of the host of every result and, with an ASN
+// database, the network they belong to.
+type ipInfo struct {
+	// proxied is set when going through a proxy, which resolves the hosts
+	// itself and is what Chrome connects to
+	proxied bool
+	asn     *maxminddb.Reader
+}
+
+// asnRecord holds the fields of GeoLite2-ASN and of the ipinfo.io and
+// iptoasn databases in MMDB format.
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+	ASN          string `maxminddb:"asn"`
+	Name         string `maxminddb:"name"`
+}
+
+// newIPInfo opens the MMDB database at asnDB, if set.
+func newIPInfo(proxied bool, asnDB string) (*ipInfo, error) {
+	info := &ipInfo{proxied: proxied}
+	if asnDB != "" {
+		db, err := maxminddb.Open(asnDB)
+		if err != nil {
+			return nil, fmt.Errorf("opening ASN database: %w", err)
+		}
+		info.asn = db
+	}
+	return info, nil
+}
+
+func (info *ipInfo) close() error {
+	if info.asn == nil {
+		return nil
+	}
+	return info.asn.Close()
+}
+
+// enrich sets the addresses and network of res. The address Chrome connected
+// to comes first.
+func (info *ipInfo) enrich(ctx context.Context, res *result) {
+	if info.proxied {
+		return
+	}
+	if res.IP != "" {
+		res.IPs = append(res.IPs, res.IP)
+	}
+	pageURL := res.FinalURL
+	if pageURL == "" {
+		pageURL = res.URL
+	}
+	if u, err := url.Parse(pageURL); err == nil && u.Hostname() != "" {
+		ctx, cancel := context.WithTimeout(ctx, ipLookupTimeout)
+		addrs, _ := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+		cancel()
+		for _, addr := range addrs {
+			if addr != res.IP {
+				res.IPs = append(res.IPs, addr)
+			}
+		}
+	}
+	if info.asn == nil || len(res.IPs) == 0 {
+		return
+	}
+	ip := net.ParseIP(res.IPs[0])
+	if ip == nil {
+		return
+	}
+	var rec asnRecord
+	if err := info.asn.Lookup(ip, &rec); err != nil {
+		return
+	}
+	res.ASN, res.ASOrg = rec.Number, rec.Organization
+	if res.ASN == 0 && rec.ASN != "" {
+		// ipinfo.io and iptoasn give it as AS13335
+		fmt.Sscanf(rec.ASN, "AS%d", &res.ASN)
+		res.ASOrg = rec.Name
+	}
+}
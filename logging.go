@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// newLogger creates the logger writing to w. -quiet only logs errors,
+// -verbose also logs every job as it starts and retries.
+func newLogger(w io.Writer, quiet, verbose, jsonLogs bool) (*slog.Logger, error) {
+	if quiet && verbose {
+		return nil, errors.New("-quiet and -verbose cannot be used together")
+	}
+	level := slog.LevelInfo
+	switch {
+	case quiet:
+		level = slog.LevelError
+	case verbose:
+		level = slog.LevelDebug
+	}
+
+	hopts := &slog.HandlerOptions{Level: level}
+	if jsonLogs {
+		return slog.New(slog.NewJSONHandler(w, hopts)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, hopts)), nil
+}
+
+// logResult logs the outcome of capturing a single URL. saveErr is an error
+// saving a successful capture.
+func logResult(logger *slog.Logger, res *result, err, saveErr error) {
+	attrs := []any{
+		"url", res.URL,
+		"attempts", res.Attempts,
+		"duration_ms", res.DurationMS,
+	}
+	switch {
+	case err != nil:
+		logger.Error("capture failed", append(attrs, "category", screenshot.ErrorCategory(err), "err", err)...)
+	case saveErr != nil:
+		logger.Error("saving capture failed", append(attrs, "category", "save", "err", saveErr)...)
+	case res.CrossOrigin != "":
+		logger.Warn("captured cross origin redirect", append(attrs, "status", res.Status, "screenshot", res.Screenshot, "final_url", res.FinalURL, "via", res.CrossOriginVia)...)
+	case res.Blank != "":
+		logger.Info("captured blank page", append(attrs, "status", res.Status, "screenshot", res.Screenshot, "blank", res.Blank)...)
+	default:
+		logger.Info("captured", append(attrs, "status", res.Status, "screenshot", res.Screenshot)...)
+	}
+}
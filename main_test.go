@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestRunFailed(t *testing.T) {
+	aborted := fmt.Errorf("%w: https://example.com failed: %w", errAborted, errors.New("net::ERR_NAME_NOT_RESOLVED"))
+	tests := []struct {
+		name         string
+		err          error
+		failed       int64
+		total        int64
+		failOnError  bool
+		maxErrorRate float64
+		want         bool
+	}{
+		{name: "ok", total: 10},
+		{name: "aborted", err: aborted, failed: 1, total: 1, want: true},
+		{name: "aborted without failures counted", err: aborted, want: true},
+		{name: "failures", failed: 1, total: 10},
+		{name: "fail on error", failed: 1, total: 10, failOnError: true, want: true},
+		{name: "below error rate", failed: 2, total: 10, maxErrorRate: 0.2},
+		{name: "above error rate", failed: 3, total: 10, maxErrorRate: 0.2, want: true},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runFailed(logger, tt.err, tt.failed, tt.total, tt.failOnError, tt.maxErrorRate); got != tt.want {
+				t.Errorf("runFailed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
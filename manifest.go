@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// manifest is written to run.json in the output directory to record how and
+// when a set of captures was made.
+type manifest struct {
+	Version string `json:"version"`
+	Go      string `json:"go"`
+	Chrome  string `json:"chrome,omitempty"`
+	// Flags are the values of all flags, with credentials redacted
+	Flags map[string]string `json:"flags"`
+	// Input is the input file, or stdin, and InputSHA256 the hash of the
+	// file
+	Input       string    `json:"input"`
+	InputSHA256 string    `json:"input_sha256,omitempty"`
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+	// Results counts the URLs by ok, failed or skipped, Errors the failed
+	// ones by error category
+	Results  map[string]int64 `json:"results"`
+	Errors   map[string]int64 `json:"errors,omitempty"`
+	Restarts int              `json:"browser_restarts"`
+}
+
+// readManifest reads run.json from the output directory, nil if there is
+// none.
+func readManifest(output string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(output, "run.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("reading run.json: %w", err)
+	}
+	return &m, nil
+}
+
+// redactedFlags have values that are secret as a whole.
+var redactedFlags = map[string]bool{"auth": true, "cookie": true}
+
+// writeManifest writes run.json for a run over inFile, or stdin if empty,
+// that started at started.
+func writeManifest(output, inFile string, started time.Time, c *screenshot.Capturer, stats *metrics) error {
+	m := manifest{
+		Version:  "(devel)",
+		Go:       runtime.Version(),
+		Flags:    map[string]string{},
+		Input:    "stdin",
+		Started:  started,
+		Finished: time.Now(),
+		Restarts: c.Restarts(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		m.Version = info.Main.Version
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m.Chrome, _ = c.BrowserVersion(ctx)
+	flag.VisitAll(func(f *flag.Flag) {
+		m.Flags[f.Name] = redactFlag(f.Name, f.Value.String())
+	})
+	if inFile != "" {
+		m.Input = inFile
+		f, err := os.Open(inFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		m.InputSHA256 = hex.EncodeToString(h.Sum(nil))
+	}
+	m.Results, m.Errors = stats.counts()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(output, "run.json"), append(data, '\n'), 0644)
+}
+
+// redactFlag hides the credentials in the value of the flag name.
+func redactFlag(name, value string) string {
+	switch {
+	case value == "":
+		return value
+	case redactedFlags[name]:
+		return "[redacted]"
+	case name == "proxy":
+		if u, err := url.Parse(value); err == nil {
+			return u.Redacted()
+		}
+	case name == "header":
+		// stringList joins the headers with ", "
+		var headers []string
+		for _, h := range strings.Split(value, ", ") {
+			name, _, _ := strings.Cut(h, ":")
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "authorization", "proxy-authorization", "cookie":
+				h = name + ": [redacted]"
+			}
+			headers = append(headers, h)
+		}
+		return strings.Join(headers, ", ")
+	}
+	return value
+}
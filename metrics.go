@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the capture duration
+// histogram.
+var durationBuckets = []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120}
+
+// metrics counts what happened to the captures for /metrics, in the
+// Prometheus text format.
+type metrics struct {
+	c *screenshot.Capturer
+
+	// queued are the captures waiting or in progress
+	queued atomic.Int64
+
+	mu       sync.Mutex
+	results  map[string]int64 // ok, failed or skipped
+	errors   map[string]int64 // by screenshot.ErrorCategory, or save
+	input    map[string]int64 // lines of the input, by what was done with them
+	buckets  []int64          // not cumulative, the last one is +Inf
+	duration float64          // sum, in seconds
+}
+
+func newMetrics(c *screenshot.Capturer) *metrics {
+	return &metrics{
+		c:       c,
+		results: map[string]int64{},
+		errors:  map[string]int64{},
+		input:   map[string]int64{},
+		buckets: make([]int64, len(durationBuckets)+1),
+	}
+}
+
+// queue records that a capture was queued. done must be called once it is
+// finished.
+func (m *metrics) queue() {
+	m.queued.Add(1)
+}
+
+// cancel records that a queued capture was given up on before it started.
+func (m *metrics) cancel() {
+	m.queued.Add(-1)
+}
+
+// done records a finished capture, like logResult.
+func (m *metrics) done(res *result, err, saveErr error) {
+	m.queued.Add(-1)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case err != nil:
+		m.results["failed"]++
+		m.errors[screenshot.ErrorCategory(err)]++
+	case saveErr != nil:
+		m.results["failed"]++
+		m.errors["save"]++
+	default:
+		m.results["ok"]++
+	}
+
+	seconds := float64(res.DurationMS) / 1000
+	i := sort.SearchFloat64s(durationBuckets, seconds)
+	m.buckets[i]++
+	m.duration += seconds
+}
+
+// failures returns how many captures failed, and how many were finished in
+// total, not counting skipped ones.
+func (m *metrics) failures() (failed, total int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.results["failed"], m.results["ok"] + m.results["failed"]
+}
+
+// counts returns copies of the counts of URLs by result and of the errors by
+// category.
+func (m *metrics) counts() (results, errors map[string]int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.results), maps.Clone(m.errors)
+}
+
+// skip records a URL that was not captured because it already was.
+func (m *metrics) skip() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results["skipped"]++
+}
+
+// inputLine records a line of the input that was read, or skipped for being
+// blank, malformed or too long.
+func (m *metrics) inputLine(kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.input[kind]++
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP screenshot_captures_total URLs processed, by result.")
+	fmt.Fprintln(w, "# TYPE screenshot_captures_total counter")
+	for _, res := range []string{"ok", "failed", "skipped"} {
+		fmt.Fprintf(w, "screenshot_captures_total{result=%q} %d\n", res, m.results[res])
+	}
+
+	fmt.Fprintln(w, "# HELP screenshot_errors_total Failed captures, by error category.")
+	fmt.Fprintln(w, "# TYPE screenshot_errors_total counter")
+	categories := make([]string, 0, len(m.errors))
+	for cat := range m.errors {
+		categories = append(categories, cat)
+	}
+	sort.Strings(categories)
+	for _, cat := range categories {
+		fmt.Fprintf(w, "screenshot_errors_total{category=%q} %d\n", cat, m.errors[cat])
+	}
+
+	fmt.Fprintln(w, "# HELP screenshot_capture_duration_seconds Time taken by a capture, including retries.")
+	fmt.Fprintln(w, "# TYPE screenshot_capture_duration_seconds histogram")
+	var count int64
+	for i, le := range durationBuckets {
+		count += m.buckets[i]
+		fmt.Fprintf(w, "screenshot_capture_duration_seconds_bucket{le=\"%g\"} %d\n", le, count)
+	}
+	count += m.buckets[len(durationBuckets)]
+	fmt.Fprintf(w, "screenshot_capture_duration_seconds_bucket{le=\"+Inf\"} %d\n", count)
+	fmt.Fprintf(w, "screenshot_capture_duration_seconds_sum %g\n", m.duration)
+	fmt.Fprintf(w, "screenshot_capture_duration_seconds_count %d\n", count)
+
+	fmt.Fprintln(w, "# HELP screenshot_input_lines_total Lines of the input read, or skipped for being blank, malformed or too long.")
+	fmt.Fprintln(w, "# TYPE screenshot_input_lines_total counter")
+	for _, kind := range []string{"read", "blank", "malformed", "too_long"} {
+		fmt.Fprintf(w, "screenshot_input_lines_total{kind=%q} %d\n", kind, m.input[kind])
+	}
+
+	fmt.Fprintln(w, "# HELP screenshot_queue_depth Captures waiting or in progress.")
+	fmt.Fprintln(w, "# TYPE screenshot_queue_depth gauge")
+	fmt.Fprintf(w, "screenshot_queue_depth %d\n", m.queued.Load())
+
+	fmt.Fprintln(w, "# HELP screenshot_browser_restarts_total Times Chrome was restarted after it died.")
+	fmt.Fprintln(w, "# TYPE screenshot_browser_restarts_total counter")
+	fmt.Fprintf(w, "screenshot_browser_restarts_total %d\n", m.c.Restarts())
+}
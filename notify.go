@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// notification is posted as JSON to the -notify-webhook URL. Text is a
+// readable summary, which is all a Slack incoming webhook shows.
+type notification struct {
+	Text  string `json:"text"`
+	Event string `json:"event"` // run_finished, changed or new_error
+
+	// run_finished
+	Output  string `json:"output,omitempty"`
+	OK      int64  `json:"ok,omitempty"`
+	Failed  int64  `json:"failed,omitempty"`
+	Skipped int64  `json:"skipped,omitempty"`
+	// Errors counts the failures by category
+	Errors map[string]int64 `json:"errors,omitempty"`
+
+	// changed and new_error
+	URL        string `json:"url,omitempty"`
+	Screenshot string `json:"screenshot,omitempty"`
+	Category   string `json:"category,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// pageState is what the notifier remembers about a URL between the rounds
+// of -interval.
+type pageState struct {
+	hash     [sha256.Size]byte
+	category string // of the last error, empty if it was captured
+}
+
+// notifier posts notifications to a webhook. It is safe for concurrent use.
+type notifier struct {
+	url    string
+	client *http.Client
+	logger *slog.Logger
+	wg     sync.WaitGroup
+
+	// track is set by monitor to notify about changes between rounds
+	track bool
+	mu    sync.Mutex
+	last  map[string]pageState
+}
+
+func newNotifier(webhook string, logger *slog.Logger) (*notifier, error) {
+	u, err := url.Parse(webhook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid webhook URL %q", webhook)
+	}
+	return &notifier{
+		url:    webhook,
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+		last:   map[string]pageState{},
+	}, nil
+}
+
+// notify posts n in the background, wait waits for it to be sent.
+func (nt *notifier) notify(n notification) {
+	nt.wg.Add(1)
+	go func() {
+		defer nt.wg.Done()
+		if err := nt.post(n); err != nil {
+			nt.logger.Error("notifying webhook", "event", n.Event, "err", err)
+		}
+	}()
+}
+
+func (nt *notifier) post(n notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, nt.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := nt.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+func (nt *notifier) wait() {
+	nt.wg.Wait()
+}
+
+// observe compares a capture to the previous one of its URL and notifies if
+// the screenshot changed or it failed in a way it did not before. Nothing is
+// notified for URLs seen for the first time, or unless track is set.
+func (nt *notifier) observe(res *result, err error) {
+	if !nt.track {
+		return
+	}
+	state := pageState{hash: res.ImageHash}
+	if err != nil {
+		state.category = screenshot.ErrorCategory(err)
+	}
+
+	nt.mu.Lock()
+	prev, seen := nt.last[res.URL]
+	if err != nil {
+		// keep the screenshot to compare the next successful one to
+		state.hash = prev.hash
+	}
+	nt.last[res.URL] = state
+	nt.mu.Unlock()
+	if !seen {
+		return
+	}
+
+	switch {
+	case err != nil && state.category != prev.category:
+		nt.notify(notification{
+			Text:     fmt.Sprintf("%s is failing: %s", res.URL, res.Error),
+			Event:    "new_error",
+			URL:      res.URL,
+			Category: state.category,
+			Error:    res.Error,
+		})
+	case err == nil && state.hash != prev.hash:
+		nt.notify(notification{
+			Text:       fmt.Sprintf("%s changed", res.URL),
+			Event:      "changed",
+			URL:        res.URL,
+			Screenshot: res.Screenshot,
+		})
+	}
+}
+
+// finished notifies that the run into output is done.
+func (nt *notifier) finished(output string, prog *progress) {
+	_, ok, failed, _, _ := prog.stats()
+	skipped := prog.skipped.Load()
+	nt.notify(notification{
+		Text:    fmt.Sprintf("screenshots into %s done: %d ok, %d failed, %d skipped", output, ok, failed, skipped),
+		Event:   "run_finished",
+		Output:  output,
+		OK:      ok,
+		Failed:  failed,
+		Skipped: skipped,
+		Errors:  prog.categories(),
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ocrTimeout is how long tesseract may take for a single screenshot, long
+// full page ones can take a while.
+const ocrTimeout = 2 * time.Minute
+
+// ocr extracts the text of screenshots with the tesseract command.
+type ocr struct {
+	tesseract string // path of the binary
+	lang      string // tesseract languages, e.g. eng or eng+deu
+}
+
+// newOCR looks up the tesseract binary, which is not needed unless -ocr is
+// given.
+func newOCR(tesseract, lang string) (*ocr, error) {
+	path, err := exec.LookPath(tesseract)
+	if err != nil {
+		return nil, fmt.Errorf("-ocr needs tesseract: %w", err)
+	}
+	return &ocr{tesseract: path, lang: lang}, nil
+}
+
+// extract runs tesseract on the screenshot in res and writes the text next to
+// it, as <name>.ocr.txt.
+func (o *ocr) extract(ctx context.Context, output string, res *result) error {
+	ctx, cancel := context.WithTimeout(ctx, ocrTimeout)
+	defer cancel()
+	image := filepath.Join(output, res.Screenshot)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, o.tesseract, image, "stdout", "-l", o.lang)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running tesseract: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	path := strings.TrimSuffix(image, filepath.Ext(image)) + ".ocr.txt"
+	var err error
+	res.OCRFile, err = writeArtifact(output, path, stdout.Bytes())
+	return err
+}
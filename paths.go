@@ -0,0 +1,39 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// pathClaims keeps the URLs of a run from being saved to the same files,
+// also on case-insensitive file systems like those of Windows and macOS the
+// output may be synced to, where a filename template can give two URLs paths
+// only differing in case. It is safe for concurrent use, and a nil
+// pathClaims claims nothing.
+type pathClaims struct {
+	mu sync.Mutex
+	// urls are the URLs saved, by their path in lower case
+	urls map[string]string
+}
+
+func newPathClaims() *pathClaims {
+	return &pathClaims{urls: map[string]string{}}
+}
+
+// claim returns path for the files of requestURL, with a hash of the URL
+// appended if another URL already has it or one differing only in case.
+func (pc *pathClaims) claim(path, requestURL string) string {
+	if pc == nil {
+		return path
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if owner, ok := pc.urls[strings.ToLower(path)]; ok && owner != requestURL {
+		sum := sha256.Sum256([]byte(requestURL))
+		path += "-" + hex.EncodeToString(sum[:6])
+	}
+	pc.urls[strings.ToLower(path)] = requestURL
+	return path
+}
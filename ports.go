@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// portExpander turns the bare hosts of the input into one candidate per port
+// of -ports, which are captured like any other URL. Of the candidates of a
+// host only those that work are kept, once for every distinct response, and
+// a failure only if none of them worked. It is safe for concurrent use.
+type portExpander struct {
+	ports  []string
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// groups are the candidates' hosts by candidate
+	groups map[string]*portGroup
+}
+
+// portGroup is a host expanded into candidates.
+type portGroup struct {
+	host    string
+	pending int
+	ok      bool
+	// seen are the pages kept so far by the hash of their screenshot and
+	// by their final URL
+	seen map[string]string
+}
+
+func newPortExpander(ports []string, logger *slog.Logger) *portExpander {
+	return &portExpander{ports: ports, logger: logger, groups: map[string]*portGroup{}}
+}
+
+// expand returns the candidates for requestURL, which is returned on its own
+// unless it is a host without a scheme or port.
+func (p *portExpander) expand(requestURL string) []string {
+	if strings.Contains(requestURL, "://") {
+		return []string{requestURL}
+	}
+	u, err := url.Parse("//" + requestURL)
+	if err != nil || u.Hostname() == "" || u.Port() != "" {
+		return []string{requestURL}
+	}
+	g := &portGroup{host: requestURL, pending: len(p.ports), seen: map[string]string{}}
+	candidates := make([]string, len(p.ports))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, port := range p.ports {
+		c := *u
+		c.Host = net.JoinHostPort(u.Hostname(), port)
+		candidates[i] = strings.TrimPrefix(c.String(), "//")
+		p.groups[candidates[i]] = g
+	}
+	return candidates
+}
+
+// keep reports whether the capture of the candidate requestURL led to should
+// be handled, and sets its Input to the host it is a candidate of. err is
+// replaced for the last failure of a host none of whose candidates worked.
+func (p *portExpander) keep(requestURL string, res *result, err *error) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	g, ok := p.groups[requestURL]
+	if !ok {
+		return true
+	}
+	delete(p.groups, requestURL)
+	g.pending--
+	res.Input = g.host
+
+	if *err != nil {
+		if g.pending > 0 || g.ok {
+			p.logger.Debug("port did not work", "url", requestURL, "err", *err)
+			return false
+		}
+		*err = fmt.Errorf("no port of %s worked for %s, last error: %w", strings.Join(p.ports, ","), g.host, *err)
+		return true
+	}
+	keys := []string{res.FinalURL}
+	if res.Image != nil {
+		keys = append(keys, string(res.ImageHash[:]))
+	}
+	for _, key := range keys {
+		if same, ok := g.seen[key]; ok && key != "" {
+			p.logger.Debug("skipping, same page as another port", "url", res.URL, "same_as", same)
+			return false
+		}
+	}
+	for _, key := range keys {
+		if key != "" {
+			g.seen[key] = res.URL
+		}
+	}
+	g.ok = true
+	return true
+}
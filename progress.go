@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progress counts the finished jobs of a run.
+type progress struct {
+	total   int // 0 if unknown, like when reading stdin
+	started time.Time
+
+	ok, failed, skipped atomic.Int64
+
+	mu sync.Mutex
+	// errors counts the failed captures by screenshot.ErrorCategory, or
+	// save, including those skipped by their -on-error policy
+	errors map[string]int64
+}
+
+func newProgress(total int) *progress {
+	return &progress{total: total, started: time.Now(), errors: map[string]int64{}}
+}
+
+func (p *progress) done(err error) {
+	if err != nil {
+		p.failed.Add(1)
+	} else {
+		p.ok.Add(1)
+	}
+}
+
+func (p *progress) skip() {
+	p.skipped.Add(1)
+}
+
+// failure counts a failed capture by the category of its error.
+func (p *progress) failure(category string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errors[category]++
+}
+
+// categories returns a copy of the counts of failure.
+func (p *progress) categories() map[string]int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return maps.Clone(p.errors)
+}
+
+// stats returns the counts and, if the total is known, the estimated time
+// left.
+func (p *progress) stats() (done, ok, failed int64, rate float64, eta time.Duration) {
+	ok, failed = p.ok.Load(), p.failed.Load()
+	done = ok + failed + p.skipped.Load()
+	rate = float64(ok+failed) / time.Since(p.started).Seconds()
+	if p.total > 0 && rate > 0 {
+		left := int64(p.total) - done
+		eta = time.Duration(float64(left)/rate) * time.Second
+	}
+	return done, ok, failed, rate, eta
+}
+
+func (p *progress) String() string {
+	done, ok, failed, rate, eta := p.stats()
+	if p.total == 0 {
+		return fmt.Sprintf("%d done, %d ok, %d failed, %.1f/s", done, ok, failed, rate)
+	}
+	return fmt.Sprintf("%d/%d (%.1f%%), %d ok, %d failed, %.1f/s, ETA %s",
+		done, p.total, 100*float64(done)/float64(p.total), ok, failed, rate, eta.Round(time.Second))
+}
+
+// report shows the progress until stop is closed, as a live status line on
+// a terminal or else by logging it every interval.
+func (p *progress) report(stop <-chan struct{}, sw *statusWriter, logger *slog.Logger, interval time.Duration) {
+	if sw.tty {
+		interval = time.Second
+	}
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			if sw.tty {
+				sw.finish(p.String())
+			}
+			return
+		case <-t.C:
+		}
+		if sw.tty {
+			sw.setStatus(p.String())
+			continue
+		}
+		done, ok, failed, rate, eta := p.stats()
+		attrs := []any{"done", done, "ok", ok, "failed", failed, "rate", fmt.Sprintf("%.1f/s", rate)}
+		if p.total > 0 {
+			attrs = append(attrs, "total", p.total, "eta", eta.Round(time.Second))
+		}
+		logger.Info("progress", attrs...)
+	}
+}
+
+// statusWriter writes to a terminal that shows a status line below
+// everything else written to it. It is safe for concurrent use.
+type statusWriter struct {
+	tty bool
+
+	mu     sync.Mutex
+	w      io.Writer
+	status string
+}
+
+func newStatusWriter(f *os.File) *statusWriter {
+	fi, err := f.Stat()
+	tty := err == nil && fi.Mode()&os.ModeCharDevice != 0
+	return &statusWriter{tty: tty, w: f}
+}
+
+const clearLine = "\r\x1b[K"
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.status == "" {
+		return sw.w.Write(p)
+	}
+	io.WriteString(sw.w, clearLine)
+	n, err := sw.w.Write(p)
+	io.WriteString(sw.w, sw.status)
+	return n, err
+}
+
+func (sw *statusWriter) setStatus(status string) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.status = status
+	io.WriteString(sw.w, clearLine+status)
+}
+
+// finish replaces the status line with a last one that stays.
+func (sw *statusWriter) finish(status string) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.status = ""
+	io.WriteString(sw.w, clearLine+status+"\n")
+}
+
+// countLines counts the lines of the file at path, for the total of a run.
+func countLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// counted like readInput reads them, however long they are
+	n := 0
+	last := byte('\n')
+	buf := make([]byte, 64<<10)
+	for {
+		read, err := f.Read(buf)
+		if read > 0 {
+			n += bytes.Count(buf[:read], []byte{'\n'})
+			last = buf[read-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		// without a newline at the end
+		n++
+	}
+	return n, nil
+}
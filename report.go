@@ -0,0 +1,551 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reportPageSize is how many captures serve-report sends at a time.
+const reportPageSize = 200
+
+// runServeReport implements the serve-report subcommand, serving the
+// captures of an output directory, or of every run indexed in a -db
+// database, in an interactive viewer.
+func runServeReport(args []string) error {
+	fset := flag.NewFlagSet("serve-report", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: screenshot serve-report [flags] <output dir>")
+		fmt.Fprintln(fset.Output(), "       screenshot serve-report [flags] -db <database>")
+		fset.PrintDefaults()
+	}
+	var addr string
+	fset.StringVar(&addr, "addr", "127.0.0.1:8081", "address to serve the viewer on")
+	var dbPath string
+	fset.StringVar(&dbPath, "db", "", "SQLite database written with -db to show the captures of all its runs from, instead of an output directory")
+	fset.Parse(args)
+
+	var src reportSource
+	switch {
+	case dbPath != "" && fset.NArg() == 0:
+		db, err := openResultDB(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.close()
+		src = &dbReport{db: db.db}
+	case dbPath == "" && fset.NArg() == 1:
+		src = &dirReport{output: fset.Arg(0)}
+	default:
+		fset.Usage()
+		os.Exit(2)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(reportHTML))
+	})
+	mux.HandleFunc("GET /api/captures", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := reportFilter{
+			Query:  strings.ToLower(q.Get("q")),
+			Status: q.Get("status"),
+			Tech:   q.Get("tech"),
+			Tag:    q.Get("tag"),
+		}
+		f.Offset, _ = strconv.Atoi(q.Get("offset"))
+		page, err := src.captures(f)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+	mux.Handle("GET /files/", src)
+	fmt.Fprintf(os.Stderr, "serving the report on http://%s\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// reportSource lists the captures shown by serve-report and serves their
+// files below /files/.
+type reportSource interface {
+	http.Handler
+	captures(f reportFilter) (*reportPage, error)
+}
+
+// reportFilter selects the captures to show. Query is matched against the
+// URL, final URL and title.
+type reportFilter struct {
+	Query  string
+	Status string
+	Tech   string
+	Tag    string
+	Offset int
+}
+
+// reportCapture is a capture as the viewer shows it, with the URLs of its
+// files.
+type reportCapture struct {
+	URL          string    `json:"url"`
+	FinalURL     string    `json:"final_url,omitempty"`
+	Status       int64     `json:"status,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	Image        string    `json:"image,omitempty"`
+	Thumbnail    string    `json:"thumbnail,omitempty"`
+	Technologies []string  `json:"technologies,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Started      time.Time `json:"started"`
+}
+
+// reportPage is a page of the captures matching a filter, along with the
+// values there are to filter by.
+type reportPage struct {
+	Total        int             `json:"total"`
+	Captures     []reportCapture `json:"captures"`
+	Statuses     []int64         `json:"statuses"`
+	Technologies []string        `json:"technologies"`
+	Tags         []string        `json:"tags"`
+}
+
+// matches reports whether c passes f.
+func (f reportFilter) matches(c *reportCapture) bool {
+	if f.Query != "" && !strings.Contains(strings.ToLower(c.URL+" "+c.FinalURL+" "+c.Title), f.Query) {
+		return false
+	}
+	if f.Status != "" && strconv.FormatInt(c.Status, 10) != f.Status {
+		return false
+	}
+	if f.Tech != "" && !slices.Contains(c.Technologies, f.Tech) {
+		return false
+	}
+	return f.Tag == "" || slices.Contains(c.Tags, f.Tag)
+}
+
+// facets fills in the values of all captures to filter by.
+func (p *reportPage) facets(all []reportCapture) {
+	statuses := map[int64]bool{}
+	techs, tags := map[string]bool{}, map[string]bool{}
+	for _, c := range all {
+		if c.Status != 0 {
+			statuses[c.Status] = true
+		}
+		for _, t := range c.Technologies {
+			techs[t] = true
+		}
+		for _, t := range c.Tags {
+			tags[t] = true
+		}
+	}
+	p.Statuses = sortedKeys(statuses)
+	p.Technologies = sortedKeys(techs)
+	p.Tags = sortedKeys(tags)
+}
+
+func sortedKeys[K int64 | string](m map[K]bool) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// paginate sets the page of matching captures at f.Offset.
+func (p *reportPage) paginate(matching []reportCapture, f reportFilter) {
+	p.Total = len(matching)
+	start := min(max(f.Offset, 0), len(matching))
+	p.Captures = matching[start:min(start+reportPageSize, len(matching))]
+}
+
+// dirReport shows the results.jsonl of an output directory, read again
+// whenever it changed.
+type dirReport struct {
+	output string
+
+	mu       sync.Mutex
+	modified time.Time
+	all      []reportCapture
+}
+
+func (d *dirReport) captures(f reportFilter) (*reportPage, error) {
+	all, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+	var matching []reportCapture
+	for i := range all {
+		if f.matches(&all[i]) {
+			matching = append(matching, all[i])
+		}
+	}
+	page := &reportPage{}
+	page.facets(all)
+	page.paginate(matching, f)
+	return page, nil
+}
+
+func (d *dirReport) load() ([]reportCapture, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	info, err := os.Stat(filepath.Join(d.output, "results.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	if info.ModTime().Equal(d.modified) {
+		return d.all, nil
+	}
+	// failed captures are shown with their error, and their error page
+	// with -capture-errors
+	results, err := readResults(d.output)
+	if err != nil {
+		return nil, err
+	}
+	all := make([]reportCapture, 0, len(results))
+	for _, r := range results {
+		c := reportCapture{
+			URL:      r.URL,
+			FinalURL: r.FinalURL,
+			Status:   r.Status,
+			Title:    r.Title,
+			Tags:     r.Tags,
+			Error:    r.Error,
+			Started:  r.Started,
+		}
+		if r.Screenshot != "" {
+			c.Image = "/files/" + fileURL(r.Screenshot)
+		}
+		c.Thumbnail = c.Image
+		if r.Thumbnail != "" {
+			c.Thumbnail = "/files/" + fileURL(r.Thumbnail)
+		}
+		for _, t := range r.Technologies {
+			c.Technologies = append(c.Technologies, t.Name)
+		}
+		all = append(all, c)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].URL < all[j].URL })
+	d.modified, d.all = info.ModTime(), all
+	return all, nil
+}
+
+func (d *dirReport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	http.StripPrefix("/files/", http.FileServer(http.Dir(d.output))).ServeHTTP(w, r)
+}
+
+// dbReport shows the latest capture of every URL indexed in a database,
+// filtered by the database. Its files are served from the output directory
+// of their run, below /files/<run id>/. The database has no technologies.
+type dbReport struct {
+	db *sql.DB
+}
+
+func (d *dbReport) captures(f reportFilter) (*reportPage, error) {
+	where := []string{"c.id IN (SELECT MAX(id) FROM captures GROUP BY url)"}
+	var args []any
+	if f.Query != "" {
+		where = append(where, "(LOWER(c.url) LIKE ? OR LOWER(c.final_url) LIKE ? OR LOWER(c.title) LIKE ?)")
+		like := "%" + f.Query + "%"
+		args = append(args, like, like, like)
+	}
+	if f.Status != "" {
+		where = append(where, "c.status = ?")
+		args = append(args, f.Status)
+	}
+	if f.Tag != "" {
+		where = append(where, "(',' || c.tags || ',') LIKE ?")
+		args = append(args, "%,"+f.Tag+",%")
+	}
+	if f.Tech != "" {
+		where = append(where, "0")
+	}
+	cond := strings.Join(where, " AND ")
+
+	page := &reportPage{}
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM captures c WHERE "+cond, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+	rows, err := d.db.Query(`SELECT c.run_id, c.url, c.final_url, c.status, c.title, c.screenshot, c.tags, c.error, c.started
+		FROM captures c WHERE `+cond+` ORDER BY c.url LIMIT ? OFFSET ?`, append(args, reportPageSize, max(f.Offset, 0))...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var run int64
+		var c reportCapture
+		var finalURL, title, screenshot, tags, errText sql.NullString
+		var status sql.NullInt64
+		var started string
+		if err := rows.Scan(&run, &c.URL, &finalURL, &status, &title, &screenshot, &tags, &errText, &started); err != nil {
+			return nil, err
+		}
+		c.FinalURL, c.Status, c.Title, c.Error = finalURL.String, status.Int64, title.String, errText.String
+		c.Tags = parseTags(tags.String)
+		c.Started, _ = time.Parse(time.RFC3339Nano, started)
+		if screenshot.Valid {
+			c.Image = fmt.Sprintf("/files/%d/%s", run, fileURL(screenshot.String))
+			c.Thumbnail = c.Image
+		}
+		page.Captures = append(page.Captures, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return page, d.facets(page)
+}
+
+// facets fills in the statuses and tags there are in the database.
+func (d *dbReport) facets(page *reportPage) error {
+	rows, err := d.db.Query("SELECT DISTINCT status FROM captures WHERE status IS NOT NULL AND status != 0 ORDER BY status")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status int64
+		if err := rows.Scan(&status); err != nil {
+			return err
+		}
+		page.Statuses = append(page.Statuses, status)
+	}
+	tagRows, err := d.db.Query("SELECT DISTINCT tags FROM captures WHERE tags IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	defer tagRows.Close()
+	tags := map[string]bool{}
+	for tagRows.Next() {
+		var list string
+		if err := tagRows.Scan(&list); err != nil {
+			return err
+		}
+		for _, t := range parseTags(list) {
+			tags[t] = true
+		}
+	}
+	page.Tags = sortedKeys(tags)
+	return tagRows.Err()
+}
+
+func (d *dbReport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	run, file, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/files/"), "/")
+	id, err := strconv.ParseInt(run, 10, 64)
+	if !ok || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var output string
+	if err := d.db.QueryRow("SELECT output FROM runs WHERE id = ?", id).Scan(&output); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	u := *r.URL
+	u.Path = path.Clean("/" + file)
+	r2 := *r
+	r2.URL = &u
+	http.FileServer(http.Dir(output)).ServeHTTP(w, &r2)
+}
+
+// reportHTML is the viewer, a thumbnail grid filtered by the API. j and k or
+// the arrow keys move through the captures, enter opens one, c marks it for
+// comparing side by side with the next one marked, / goes to the search and
+// escape closes what is open.
+const reportHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Screenshots</title>
+<style>
+body { font-family: sans-serif; margin: 0; background: #f4f4f4; }
+#filters { position: sticky; top: 0; display: flex; gap: .5em; padding: .5em 1em; background: #fff; border-bottom: 1px solid #ddd; z-index: 1; }
+#filters input { flex: 1; padding: .4em; font-size: 1em; }
+#count { align-self: center; color: #666; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(260px, 1fr)); gap: 1em; padding: 1em; }
+.card { background: #fff; border: 2px solid #ddd; padding: .4em; overflow-wrap: anywhere; cursor: pointer; font-size: .9em; }
+.card.selected { border-color: #36c; }
+.card.marked { background: #def; }
+.card img { width: 100%; border: 1px solid #eee; }
+.status { font-weight: bold; }
+.error { color: #b00; }
+.tag { display: inline-block; background: #def; padding: 0 .3em; margin-right: .3em; }
+#more { display: block; margin: 0 auto 2em; padding: .5em 2em; }
+#overlay { display: none; position: fixed; inset: 0; background: rgba(0, 0, 0, .85); overflow: auto; z-index: 2; }
+#overlay .panes { display: flex; gap: 1em; padding: 1em; }
+#overlay .pane { flex: 1; color: #fff; overflow-wrap: anywhere; }
+#overlay img { width: 100%; background: #fff; }
+</style>
+</head>
+<body>
+<div id="filters">
+<input id="q" type="search" placeholder="Search URL or title (press / to focus)">
+<select id="status"><option value="">any status</option></select>
+<select id="tech"><option value="">any technology</option></select>
+<select id="tag"><option value="">any tag</option></select>
+<span id="count"></span>
+</div>
+<div class="grid" id="grid"></div>
+<button id="more">More</button>
+<div id="overlay"><div class="panes" id="panes"></div></div>
+<script>
+var captures = [], selected = -1, marked = [], total = 0, facetsSet = false;
+var grid = document.getElementById("grid"), overlay = document.getElementById("overlay");
+
+function el(tag, cls, text) {
+	var e = document.createElement(tag);
+	if (cls) e.className = cls;
+	if (text) e.textContent = text;
+	return e;
+}
+
+function fillSelect(id, values) {
+	var s = document.getElementById(id);
+	values.forEach(function(v) {
+		var o = el("option", "", String(v));
+		o.value = v;
+		s.appendChild(o);
+	});
+}
+
+function query(offset) {
+	var p = new URLSearchParams({offset: offset});
+	["q", "status", "tech", "tag"].forEach(function(id) {
+		var v = document.getElementById(id).value;
+		if (v) p.set(id, v);
+	});
+	return fetch("/api/captures?" + p).then(function(r) { return r.json(); });
+}
+
+function card(c, i) {
+	var d = el("div", "card");
+	if (c.thumbnail) {
+		var img = el("img");
+		img.loading = "lazy";
+		img.src = c.thumbnail;
+		d.appendChild(img);
+	}
+	d.appendChild(el("div", "", c.url));
+	if (c.status) d.appendChild(el("div", "status", String(c.status)));
+	if (c.title) d.appendChild(el("div", "", c.title));
+	if (c.tags) {
+		var tags = el("div");
+		c.tags.forEach(function(t) { tags.appendChild(el("span", "tag", t)); });
+		d.appendChild(tags);
+	}
+	if (c.error) d.appendChild(el("div", "error", c.error));
+	d.addEventListener("click", function(e) {
+		select(i);
+		if (e.shiftKey) mark(); else open([i]);
+	});
+	return d;
+}
+
+function load(reset) {
+	var offset = reset ? 0 : captures.length;
+	query(offset).then(function(page) {
+		if (reset) {
+			captures = [];
+			grid.textContent = "";
+			selected = -1;
+			marked = [];
+		}
+		if (!facetsSet) {
+			fillSelect("status", page.statuses || []);
+			fillSelect("tech", page.technologies || []);
+			fillSelect("tag", page.tags || []);
+			facetsSet = true;
+		}
+		total = page.total;
+		(page.captures || []).forEach(function(c) {
+			grid.appendChild(card(c, captures.length));
+			captures.push(c);
+		});
+		document.getElementById("count").textContent = captures.length + " of " + total;
+		document.getElementById("more").style.display = captures.length < total ? "" : "none";
+	});
+}
+
+function select(i) {
+	if (i < 0 || i >= captures.length) return;
+	if (selected >= 0) grid.children[selected].classList.remove("selected");
+	selected = i;
+	grid.children[i].classList.add("selected");
+	grid.children[i].scrollIntoView({block: "nearest"});
+	if (i >= captures.length - 5 && captures.length < total) load(false);
+}
+
+function mark() {
+	if (selected < 0) return;
+	grid.children[selected].classList.add("marked");
+	marked.push(selected);
+	if (marked.length == 2) {
+		open(marked);
+		marked.forEach(function(i) { grid.children[i].classList.remove("marked"); });
+		marked = [];
+	}
+}
+
+function open(indexes) {
+	var panes = document.getElementById("panes");
+	panes.textContent = "";
+	indexes.forEach(function(i) {
+		var c = captures[i], pane = el("div", "pane");
+		pane.appendChild(el("div", "", c.url + (c.status ? " " + c.status : "")));
+		if (c.image) {
+			var img = el("img");
+			img.src = c.image;
+			pane.appendChild(img);
+		}
+		panes.appendChild(pane);
+	});
+	overlay.style.display = "block";
+	overlay.scrollTop = 0;
+}
+
+function columns() {
+	return getComputedStyle(grid).gridTemplateColumns.split(" ").length;
+}
+
+document.addEventListener("keydown", function(e) {
+	if (e.target.tagName == "INPUT" || e.target.tagName == "SELECT") {
+		if (e.key == "Escape") e.target.blur();
+		return;
+	}
+	var open_ = overlay.style.display == "block";
+	switch (e.key) {
+	case "Escape": overlay.style.display = "none"; break;
+	case "/": e.preventDefault(); document.getElementById("q").focus(); break;
+	case "j": case "ArrowRight": select(selected + 1); if (open_) open([selected]); break;
+	case "k": case "ArrowLeft": select(selected - 1); if (open_) open([selected]); break;
+	case "ArrowDown": if (!open_) { e.preventDefault(); select(selected + columns()); } break;
+	case "ArrowUp": if (!open_) { e.preventDefault(); select(selected - columns()); } break;
+	case "Enter": if (selected >= 0) open([selected]); break;
+	case "c": mark(); break;
+	}
+});
+overlay.addEventListener("click", function() { overlay.style.display = "none"; });
+document.getElementById("more").addEventListener("click", function() { load(false); });
+var timer;
+document.getElementById("q").addEventListener("input", function() {
+	clearTimeout(timer);
+	timer = setTimeout(function() { load(true); }, 200);
+});
+["status", "tech", "tag"].forEach(function(id) {
+	document.getElementById(id).addEventListener("change", function() { load(true); });
+});
+load(true);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// result is the outcome of capturing a single URL.
+type result struct {
+	screenshot.Result
+	Screenshot  string `json:"screenshot,omitempty"`  // relative to the output directory
+	HTMLFile    string `json:"html,omitempty"`        // relative to the output directory
+	MHTMLFile   string `json:"mhtml,omitempty"`       // relative to the output directory
+	A11yFile    string `json:"a11y,omitempty"`        // accessibility tree, relative to the output directory
+	FaviconFile string `json:"favicon,omitempty"`     // relative to the output directory
+	OCRFile     string `json:"ocr,omitempty"`         // text extracted by -ocr, relative to the output directory
+	PDFFile     string `json:"pdf,omitempty"`         // relative to the output directory
+	HARFile     string `json:"har,omitempty"`         // relative to the output directory
+	ConsoleFile string `json:"console_log,omitempty"` // relative to the output directory
+	VideoFile   string `json:"video,omitempty"`       // relative to the output directory
+	Thumbnail   string `json:"thumbnail,omitempty"`   // relative to the output directory
+	// SizeFiles are the screenshots in the other -sizes, RequestFiles the
+	// saved request headers and Bodies the saved response bodies, relative
+	// to the output directory.
+	SizeFiles    []string `json:"sizes,omitempty"`
+	RequestFiles []string `json:"requests,omitempty"`
+	Bodies       []string `json:"bodies,omitempty"`
+	PHash        string   `json:"phash,omitempty"` // perceptual hash, only with -cluster
+	Cluster      int      `json:"cluster,omitempty"`
+	// Depth is how many links away from the input a page found with
+	// -crawl-depth is, CrawledFrom the page linking to it.
+	Depth       int    `json:"depth,omitempty"`
+	CrawledFrom string `json:"crawled_from,omitempty"`
+	// IPs are the addresses of the host, the one Chrome connected to first,
+	// and ASN and ASOrg the network of the first with -asn-db.
+	IPs   []string `json:"ips,omitempty"`
+	ASN   uint     `json:"asn,omitempty"`
+	ASOrg string   `json:"as_org,omitempty"`
+	// IPVariant is set on the extra captures of -also-ip, one of the
+	// variant* constants, with VariantOf the page it is one of. Differs
+	// lists what of status, title and screenshot is not the same.
+	IPVariant string   `json:"ip_variant,omitempty"`
+	VariantOf string   `json:"variant_of,omitempty"`
+	Differs   []string `json:"differs,omitempty"`
+	// CrossOrigin is the host a page redirected to if it is not its own,
+	// only with -flag-cross-origin-redirects, and CrossOriginVia the type
+	// of the redirect leaving it, http or a client side reason.
+	CrossOrigin    string `json:"cross_origin,omitempty"`
+	CrossOriginVia string `json:"cross_origin_via,omitempty"`
+	// Unchanged is set with -only-changed if the screenshot is the same as
+	// the previous one of the URL, which Screenshot then points to.
+	Unchanged bool   `json:"unchanged,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	// name, if set, is the path to save at given by structured input,
+	// relative to the output directory and without extension
+	name string
+}
+
+// files returns the files written for r, relative to the output directory.
+// Some are empty if they were not written.
+func (r *result) files() []string {
+	files := []string{r.Screenshot, r.HTMLFile, r.MHTMLFile, r.A11yFile, r.FaviconFile, r.OCRFile, r.PDFFile, r.HARFile, r.ConsoleFile, r.VideoFile, r.Thumbnail}
+	files = append(files, r.SizeFiles...)
+	files = append(files, r.RequestFiles...)
+	return append(files, r.Bodies...)
+}
+
+// resultWriter writes results as JSON lines, either to results.jsonl in the
+// output directory or to stdout. It is safe for concurrent use.
+type resultWriter struct {
+	mu  sync.Mutex
+	w   io.WriteCloser
+	enc *json.Encoder
+}
+
+// newResultWriter creates the writer. If appendResults is set an existing
+// results.jsonl is appended to instead of truncated.
+func newResultWriter(output string, stdout, appendResults bool) (*resultWriter, error) {
+	var w io.WriteCloser = os.Stdout
+	if !stdout {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if appendResults {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		f, err := os.OpenFile(filepath.Join(output, "results.jsonl"), flags, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	return &resultWriter{w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (rw *resultWriter) write(r *result) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return rw.enc.Encode(r)
+}
+
+func (rw *resultWriter) close() error {
+	if rw.w == os.Stdout {
+		return nil
+	}
+	return rw.w.Close()
+}
+
+// loadResults reads the successful captures from results.jsonl in the output
+// directory, if there is one. Failed URLs are left out as they will be tried
+// again, also those with a screenshot of their error page.
+func loadResults(output string) ([]result, error) {
+	all, err := readResults(output)
+	if err != nil {
+		return nil, err
+	}
+	var results []result
+	for _, r := range all {
+		if r.Screenshot != "" && r.Error == "" {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// readResults reads every result from results.jsonl in the output
+// directory, also the failed ones, if there is one.
+func readResults(output string) ([]result, error) {
+	f, err := os.Open(filepath.Join(output, "results.jsonl"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var results []result
+	dec := json.NewDecoder(f)
+	for {
+		var r result
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.Name(), err)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
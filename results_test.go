@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadResults(t *testing.T) {
+	output := t.TempDir()
+	lines := `{"url":"https://ok.example.com","screenshot":"ok.example.com.png"}
+{"url":"https://failed.example.com","error":"net::ERR_CONNECTION_REFUSED"}
+{"url":"https://error-page.example.com","screenshot":"errors/error-page.example.com.png","error":"status 500"}
+`
+	if err := os.WriteFile(filepath.Join(output, "results.jsonl"), []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err := loadResults(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].URL != "https://ok.example.com" {
+		t.Errorf("loadResults() = %+v, want only https://ok.example.com", results)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+	"golang.org/x/term"
+)
+
+// reviewTags are the tags a capture can be given in review, by key.
+var reviewTags = map[byte]string{
+	'k': "keep",
+	'i': "interesting",
+	'x': "ignore",
+}
+
+// runReview implements the review subcommand, walking through the captures
+// of an output directory to tag them. Tags are kept in tags.json in the
+// output directory, so a review can be stopped and picked up again, and
+// written as "tag url" lines to tagged.txt when done.
+func runReview(args []string) error {
+	fset := flag.NewFlagSet("review", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: screenshot review [flags] <output dir>")
+		fset.PrintDefaults()
+	}
+	var display string
+	fset.StringVar(&display, "display", "", "how to show screenshots, kitty to draw them in the terminal or viewer to open them, by default kitty in kitty terminals")
+	var viewer string
+	fset.StringVar(&viewer, "viewer", "", "command to open screenshots with, by default xdg-open or open")
+	var all bool
+	fset.BoolVar(&all, "all", false, "If true, also shows captures already tagged")
+	fset.Parse(args)
+	if fset.NArg() != 1 {
+		fset.Usage()
+		os.Exit(2)
+	}
+	output := fset.Arg(0)
+
+	if display == "" {
+		display = "viewer"
+		if os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty" {
+			display = "kitty"
+		}
+	}
+	if display != "kitty" && display != "viewer" {
+		return fmt.Errorf("unknown display %q, must be kitty or viewer", display)
+	}
+	if viewer == "" {
+		viewer = "xdg-open"
+		if runtime.GOOS == "darwin" {
+			viewer = "open"
+		}
+	}
+
+	results, err := loadResults(output)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no captures in %s", output)
+	}
+	tags, err := loadTags(output)
+	if err != nil {
+		return err
+	}
+	var todo []result
+	for _, r := range results {
+		if all || tags[r.URL] == "" {
+			todo = append(todo, r)
+		}
+	}
+
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("review needs a terminal: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	key := make([]byte, 1)
+	for i := 0; i < len(todo); {
+		r := todo[i]
+		// raw mode needs explicit carriage returns
+		fmt.Print("\x1b[2J\x1b[H")
+		fmt.Printf("[%d/%d] %s\r\n", i+1, len(todo), r.URL)
+		fmt.Printf("%d %s  %s\r\n", r.Status, r.Title, tags[r.URL])
+		path := filepath.Join(output, r.Screenshot)
+		if display == "kitty" {
+			if err := showKitty(os.Stdout, path); err != nil {
+				fmt.Printf("%v\r\n", err)
+			}
+		} else if err := exec.Command(viewer, path).Start(); err != nil {
+			fmt.Printf("opening %s: %v\r\n", path, err)
+		}
+		fmt.Print("\r\n[k]eep [i]nteresting [x] ignore, [n]ext [b]ack [q]uit ")
+
+		if _, err := os.Stdin.Read(key); err != nil {
+			return err
+		}
+		switch key[0] {
+		case 'q', 3: // ctrl-c
+			i = len(todo)
+		case 'b':
+			if i > 0 {
+				i--
+			}
+		case 'n', ' ':
+			i++
+		default:
+			tag, ok := reviewTags[key[0]]
+			if !ok {
+				continue
+			}
+			tags[r.URL] = tag
+			if err := saveTags(output, tags); err != nil {
+				return err
+			}
+			i++
+		}
+	}
+	fmt.Print("\x1b[2J\x1b[H")
+	term.Restore(fd, state)
+	return exportTags(output, tags)
+}
+
+// showKitty draws the image at path in the terminal with the kitty graphics
+// protocol, scaled to the width of the terminal.
+func showKitty(w io.Writer, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	cols, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		cols = 80
+	}
+	// a thumbnail is plenty for a terminal and keeps the escape codes short
+	thumb, err := screenshot.Thumbnail(data, 1280)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+
+	// sent in chunks of at most 4096 bytes, m=1 on all but the last
+	payload := base64.StdEncoding.EncodeToString(buf.Bytes())
+	for first := true; payload != ""; first = false {
+		chunk := payload[:min(4096, len(payload))]
+		payload = payload[len(chunk):]
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(w, "\x1b_Ga=T,f=100,c=%d,m=%d;%s\x1b\\", cols, more, chunk)
+		} else {
+			fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	fmt.Fprint(w, "\r\n")
+	return nil
+}
+
+func loadTags(output string) (map[string]string, error) {
+	tags := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(output, "tags.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return tags, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, fmt.Errorf("reading tags.json: %w", err)
+	}
+	return tags, nil
+}
+
+func saveTags(output string, tags map[string]string) error {
+	data, err := json.MarshalIndent(tags, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(output, "tags.json"), data, 0644)
+}
+
+// exportTags writes the tagged URLs to tagged.txt, sorted by tag and URL, and
+// tells where they are.
+func exportTags(output string, tags map[string]string) error {
+	lines := make([]string, 0, len(tags))
+	for u, tag := range tags {
+		lines = append(lines, tag+" "+u)
+	}
+	sort.Strings(lines)
+	path := filepath.Join(output, "tagged.txt")
+	var data string
+	if len(lines) > 0 {
+		data = strings.Join(lines, "\n") + "\n"
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("%d tagged captures written to %s\n", len(lines), path)
+	return nil
+}
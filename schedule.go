@@ -0,0 +1,165 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// schedulerLookahead is how many URLs the scheduler reads ahead of the
+// captures to find ones of other hosts while a host is at its limit.
+const schedulerLookahead = 10000
+
+// scheduler orders the URLs of a batch so no host has more than limit
+// captures running at once, taking turns between the hosts that have URLs
+// waiting. With no limit URLs are started in the order they were added. It
+// is safe for concurrent use.
+type scheduler struct {
+	limit int
+	// wake is signalled when a capture finished, as that may let the next
+	// URL of its host run
+	wake chan struct{}
+
+	mu      sync.Mutex
+	queues  map[string][]string // waiting URLs by host
+	hosts   []string            // hosts with waiting URLs, in turn order
+	active  map[string]int      // running captures by host
+	waiting int
+}
+
+func newScheduler(limit int) *scheduler {
+	return &scheduler{
+		limit:  limit,
+		wake:   make(chan struct{}, 1),
+		queues: map[string][]string{},
+		active: map[string]int{},
+	}
+}
+
+// full reports whether no more URLs should be added until some were
+// started.
+func (s *scheduler) full() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limit <= 0 {
+		return s.waiting > 0
+	}
+	return s.waiting >= schedulerLookahead
+}
+
+// empty reports whether no URLs are waiting.
+func (s *scheduler) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiting == 0
+}
+
+// idle reports whether no URLs are waiting or running.
+func (s *scheduler) idle() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiting == 0 && len(s.active) == 0
+}
+
+func (s *scheduler) add(requestURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	host := hostKey(requestURL)
+	if len(s.queues[host]) == 0 {
+		s.hosts = append(s.hosts, host)
+	}
+	s.queues[host] = append(s.queues[host], requestURL)
+	s.waiting++
+}
+
+// push adds requestURL in front of every waiting URL, to be started next
+// unless its host is at the limit.
+func (s *scheduler) push(requestURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	host := hostKey(requestURL)
+	for i, h := range s.hosts {
+		if h == host {
+			s.hosts = append(s.hosts[:i], s.hosts[i+1:]...)
+			break
+		}
+	}
+	s.hosts = append([]string{host}, s.hosts...)
+	s.queues[host] = append([]string{requestURL}, s.queues[host]...)
+	s.waiting++
+}
+
+// next returns the URL to start next, if any host with waiting URLs is below
+// the limit. It stays next until start is called.
+func (s *scheduler) next() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, host := range s.hosts {
+		if s.limit <= 0 || s.active[host] < s.limit {
+			return s.queues[host][0], true
+		}
+	}
+	return "", false
+}
+
+// start records that requestURL, as returned by next, was started. Its host
+// goes to the back of the line.
+func (s *scheduler) start(requestURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	host := hostKey(requestURL)
+	s.active[host]++
+	s.waiting--
+	s.queues[host] = s.queues[host][1:]
+	for i, h := range s.hosts {
+		if h == host {
+			s.hosts = append(s.hosts[:i], s.hosts[i+1:]...)
+			break
+		}
+	}
+	if len(s.queues[host]) > 0 {
+		s.hosts = append(s.hosts, host)
+	} else {
+		delete(s.queues, host)
+	}
+}
+
+// done records that the capture of requestURL finished.
+func (s *scheduler) done(requestURL string) {
+	s.mu.Lock()
+	host := hostKey(requestURL)
+	if s.active[host]--; s.active[host] <= 0 {
+		delete(s.active, host)
+	}
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// drain removes and returns the waiting URLs.
+func (s *scheduler) drain() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var urls []string
+	for _, host := range s.hosts {
+		urls = append(urls, s.queues[host]...)
+	}
+	s.queues, s.hosts, s.waiting = map[string][]string{}, nil, 0
+	return urls
+}
+
+// hostKey is the host requestURL is limited by, which may be a bare host
+// name or host:port from the input.
+func hostKey(requestURL string) string {
+	raw := requestURL
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return requestURL
+	}
+	return strings.ToLower(u.Hostname())
+}
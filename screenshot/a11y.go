@@ -0,0 +1,115 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chromedp/cdproto/accessibility"
+	"github.com/chromedp/chromedp"
+)
+
+// AXNode is a node of the accessibility tree of a page, as assistive
+// technology like screen readers sees it.
+type AXNode struct {
+	Role        string `json:"role,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Value       any    `json:"value,omitempty"`
+	// Properties are the states and ARIA attributes of the node, like
+	// focusable, level or expanded.
+	Properties map[string]any `json:"properties,omitempty"`
+	Children   []*AXNode      `json:"children,omitempty"`
+}
+
+// saveA11y stores the accessibility tree of the page in res if A11y is set.
+func (c *Capturer) saveA11y(res **AXNode) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.A11y {
+			return nil
+		}
+		nodes, err := accessibility.GetFullAXTree().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("getting accessibility tree: %w", err)
+		}
+		*res = axTree(nodes)
+		return nil
+	})
+}
+
+// axTree builds the tree of nodes, leaving out the ignored ones but not
+// their children.
+func axTree(nodes []*accessibility.Node) *AXNode {
+	byID := make(map[accessibility.NodeID]*accessibility.Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.NodeID] = n
+	}
+	seen := map[accessibility.NodeID]bool{}
+	var build func(n *accessibility.Node) []*AXNode
+	build = func(n *accessibility.Node) []*AXNode {
+		if seen[n.NodeID] {
+			return nil
+		}
+		seen[n.NodeID] = true
+		var children []*AXNode
+		for _, id := range n.ChildIDs {
+			if child, ok := byID[id]; ok {
+				children = append(children, build(child)...)
+			}
+		}
+		role := axString(n.Role)
+		if n.Ignored || role == "InlineTextBox" {
+			// inline text boxes repeat the text of their parent
+			return children
+		}
+		node := &AXNode{
+			Role:        role,
+			Name:        axString(n.Name),
+			Description: axString(n.Description),
+			Value:       axValue(n.Value),
+			Children:    children,
+		}
+		for _, p := range n.Properties {
+			v := axValue(p.Value)
+			if v == nil {
+				continue
+			}
+			if node.Properties == nil {
+				node.Properties = map[string]any{}
+			}
+			node.Properties[string(p.Name)] = v
+		}
+		return []*AXNode{node}
+	}
+
+	root := &AXNode{}
+	for _, n := range nodes {
+		if n.ParentID == "" {
+			root.Children = append(root.Children, build(n)...)
+		}
+	}
+	if len(root.Children) == 1 {
+		return root.Children[0]
+	}
+	return root
+}
+
+// axValue decodes the JSON value of v, nil if it has none.
+func axValue(v *accessibility.Value) any {
+	if v == nil || len(v.Value) == 0 {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(v.Value, &value); err != nil {
+		return nil
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return nil
+	}
+	return value
+}
+
+func axString(v *accessibility.Value) string {
+	s, _ := axValue(v).(string)
+	return s
+}
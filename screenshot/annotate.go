@@ -0,0 +1,91 @@
+package screenshot
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	bannerPadding = 6
+	bannerLine    = 16 // line height, the font is 13 pixels high
+)
+
+var (
+	bannerBackground = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	bannerText       = color.RGBA{0xf0, 0xf0, 0xf0, 0xff}
+)
+
+// bannerLines returns the text of the banner annotate puts on res.
+func bannerLines(res *Result) []string {
+	first := res.URL
+	if res.FinalURL != "" && res.FinalURL != res.URL {
+		first += " -> " + res.FinalURL
+	}
+	second := res.Started.UTC().Format(time.RFC3339)
+	if res.Status != 0 {
+		second = fmt.Sprintf("%d  %s", res.Status, second)
+	}
+	return []string{first, second}
+}
+
+// annotate puts a banner with the requested and final URL, status and time
+// of capture above the screenshots in res if Annotate is set.
+func (c *Capturer) annotate(res *Result) error {
+	if !c.opts.Annotate {
+		return nil
+	}
+	var err error
+	if res.Image, err = c.annotateImage(res, res.Image); err != nil {
+		return err
+	}
+	for i := range res.Sizes {
+		if res.Sizes[i].Image, err = c.annotateImage(res, res.Sizes[i].Image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// annotateImage returns data, a screenshot of res, with the banner above it.
+func (c *Capturer) annotateImage(res *Result, data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("annotating screenshot: %w", err)
+	}
+
+	// the font has a fixed size, so draw it at 1x and scale it up along
+	// with the page
+	scale := int(math.Max(1, math.Round(c.opts.Scale)))
+	b := img.Bounds()
+	lines := bannerLines(res)
+	banner := image.NewRGBA(image.Rect(0, 0, b.Dx()/scale, 2*bannerPadding+len(lines)*bannerLine))
+	draw.Draw(banner, banner.Bounds(), image.NewUniform(bannerBackground), image.Point{}, draw.Src)
+	d := font.Drawer{Dst: banner, Src: image.NewUniform(bannerText), Face: basicfont.Face7x13}
+	maxChars := (banner.Bounds().Dx() - 2*bannerPadding) / basicfont.Face7x13.Advance
+	for i, line := range lines {
+		if len(line) > maxChars && maxChars > 3 {
+			line = line[:maxChars-3] + "..."
+		}
+		d.Dot = fixed.P(bannerPadding, bannerPadding+i*bannerLine+basicfont.Face7x13.Ascent)
+		d.DrawString(line)
+	}
+
+	bh := banner.Bounds().Dy() * scale
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), bh+b.Dy()))
+	draw.NearestNeighbor.Scale(out, image.Rect(0, 0, banner.Bounds().Dx()*scale, bh), banner, banner.Bounds(), draw.Src, nil)
+	draw.Draw(out, image.Rect(0, bh, b.Dx(), bh+b.Dy()), img, b.Min, draw.Src)
+
+	if data, err = c.encode(out); err != nil {
+		return nil, fmt.Errorf("annotating screenshot: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,93 @@
+package screenshot
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Reasons a page is considered blank.
+const (
+	BlankImage     = "blank image"
+	BlankErrorPage = "error page"
+	BlankEmptyBody = "empty body"
+)
+
+const (
+	// blankSamples is how many pixels per row and column blankImage looks
+	// at.
+	blankSamples = 64
+	// blankShare is the share of sampled pixels that must have the same
+	// color for a screenshot to be blank.
+	blankShare = 0.995
+	// emptyBodyText is how many characters of text a body with no images
+	// or other media can have and still be considered empty.
+	emptyBodyText = 20
+)
+
+// pageContentJS measures how much there is to see on a page.
+const pageContentJS = `(() => {
+	const body = document.body;
+	if (!body) {
+		return {text: 0, media: 0};
+	}
+	return {
+		text: body.innerText.trim().length,
+		media: body.querySelectorAll('img, svg, video, canvas, iframe, embed, object, input').length,
+	};
+})()`
+
+type pageContent struct {
+	Text  int `json:"text"`
+	Media int `json:"media"`
+}
+
+// measureContent stores the page content in res if DetectBlank is set.
+func (c *Capturer) measureContent(res *pageContent) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.DetectBlank {
+			return nil
+		}
+		return chromedp.Evaluate(pageContentJS, res).Do(ctx)
+	})
+}
+
+// blankReason returns why the captured page in res is blank, if it is.
+func blankReason(res *Result, content pageContent) string {
+	switch {
+	case strings.HasPrefix(res.FinalURL, "chrome-error://"):
+		return BlankErrorPage
+	case blankImage(res.Image):
+		return BlankImage
+	case content.Text < emptyBodyText && content.Media == 0:
+		return BlankEmptyBody
+	}
+	return ""
+}
+
+// blankImage reports whether nearly all of an encoded screenshot is a single
+// color. Images that fail to decode are not blank.
+func blankImage(data []byte) bool {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	b := img.Bounds()
+	counts := map[[3]uint32]int{}
+	most := 0
+	for y := 0; y < blankSamples; y++ {
+		for x := 0; x < blankSamples; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x*b.Dx()/blankSamples, b.Min.Y+y*b.Dy()/blankSamples).RGBA()
+			// ignore compression noise
+			key := [3]uint32{r >> 12, g >> 12, bl >> 12}
+			counts[key]++
+			if counts[key] > most {
+				most = counts[key]
+			}
+		}
+	}
+	return float64(most) >= blankShare*blankSamples*blankSamples
+}
@@ -0,0 +1,110 @@
+package screenshot
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// blockTypes maps the resource categories that can be blocked to the
+// resource types Chrome reports for them.
+var blockTypes = map[string][]network.ResourceType{
+	"images":      {network.ResourceTypeImage},
+	"fonts":       {network.ResourceTypeFont},
+	"media":       {network.ResourceTypeMedia},
+	"stylesheets": {network.ResourceTypeStylesheet},
+}
+
+// analyticsHosts are blocked, including their subdomains, by the analytics
+// category.
+var analyticsHosts = []string{
+	"google-analytics.com",
+	"googletagmanager.com",
+	"googleadservices.com",
+	"doubleclick.net",
+	"analytics.google.com",
+	"connect.facebook.net",
+	"hotjar.com",
+	"mixpanel.com",
+	"segment.com",
+	"segment.io",
+	"scorecardresearch.com",
+	"newrelic.com",
+	"nr-data.net",
+	"clarity.ms",
+	"matomo.cloud",
+}
+
+// blocker decides which requests of a page are failed before they load.
+// The page itself is never blocked.
+type blocker struct {
+	types     map[network.ResourceType]bool
+	analytics bool
+	patterns  []*regexp.Regexp
+}
+
+// newBlocker creates a blocker for the given categories (images, fonts,
+// media, stylesheets or analytics) and URL patterns, where * matches any
+// number of characters. It returns nil if nothing is to be blocked.
+func newBlocker(categories, patterns []string) (*blocker, error) {
+	if len(categories) == 0 && len(patterns) == 0 {
+		return nil, nil
+	}
+	b := &blocker{types: map[network.ResourceType]bool{}}
+	for _, cat := range categories {
+		if cat == "analytics" {
+			b.analytics = true
+			continue
+		}
+		types, ok := blockTypes[cat]
+		if !ok {
+			return nil, fmt.Errorf("unknown resource category %q to block, must be one of images, fonts, media, stylesheets or analytics", cat)
+		}
+		for _, t := range types {
+			b.types[t] = true
+		}
+	}
+	for _, p := range patterns {
+		quoted := strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*")
+		re, err := regexp.Compile("^" + quoted + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid block pattern %q: %w", p, err)
+		}
+		b.patterns = append(b.patterns, re)
+	}
+	return b, nil
+}
+
+// blocks reports whether a request for rawURL of type t should be failed. A
+// nil blocker blocks nothing.
+func (b *blocker) blocks(rawURL string, t network.ResourceType) bool {
+	if b == nil || t == network.ResourceTypeDocument {
+		return false
+	}
+	if b.types[t] {
+		return true
+	}
+	if b.analytics {
+		if u, err := url.Parse(rawURL); err == nil && isAnalyticsHost(u.Hostname()) {
+			return true
+		}
+	}
+	for _, re := range b.patterns {
+		if re.MatchString(rawURL) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAnalyticsHost(host string) bool {
+	for _, h := range analyticsHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
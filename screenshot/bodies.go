@@ -0,0 +1,96 @@
+package screenshot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// ResponseBody is the body of a response received while loading a page.
+type ResponseBody struct {
+	URL      string
+	Status   int64
+	MIMEType string
+	Body     []byte
+}
+
+// bodyRecorder keeps track of the responses of a tab whose bodies can be
+// fetched once they finished loading.
+type bodyRecorder struct {
+	all     bool
+	frameID cdp.FrameID
+
+	mu        sync.Mutex
+	responses map[network.RequestID]*network.Response
+	order     []network.RequestID // finished, in the order they finished
+}
+
+// recordBodies starts recording the responses of the main document in the
+// tab behind ctx, or of every request if all is set.
+func recordBodies(ctx context.Context, all bool) *bodyRecorder {
+	r := &bodyRecorder{
+		all:       all,
+		frameID:   cdp.FrameID(chromedp.FromContext(ctx).Target.TargetID),
+		responses: map[network.RequestID]*network.Response{},
+	}
+	chromedp.ListenTarget(ctx, r.handle)
+	return r
+}
+
+func (r *bodyRecorder) handle(ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case *network.EventResponseReceived:
+		if r.all || (ev.FrameID == r.frameID && ev.Type == network.ResourceTypeDocument) {
+			r.responses[ev.RequestID] = ev.Response
+		}
+	case *network.EventLoadingFinished:
+		if _, ok := r.responses[ev.RequestID]; ok {
+			r.order = append(r.order, ev.RequestID)
+		}
+	}
+}
+
+// fetch stores the bodies of the finished responses in res. Only the last
+// response of every URL is kept, and bodies Chrome no longer has are
+// skipped.
+func (r *bodyRecorder) fetch(res *Result) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		r.mu.Lock()
+		order := append([]network.RequestID(nil), r.order...)
+		r.mu.Unlock()
+
+		if !r.all && len(order) > 0 {
+			// earlier documents were navigated away from
+			order = order[len(order)-1:]
+		}
+		seen := map[string]int{}
+		res.Responses = nil
+		for _, id := range order {
+			r.mu.Lock()
+			resp := r.responses[id]
+			r.mu.Unlock()
+
+			body, err := network.GetResponseBody(id).Do(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				continue
+			}
+			b := ResponseBody{URL: resp.URL, Status: resp.Status, MIMEType: resp.MimeType, Body: body}
+			if i, ok := seen[resp.URL]; ok {
+				res.Responses[i] = b
+				continue
+			}
+			seen[resp.URL] = len(res.Responses)
+			res.Responses = append(res.Responses, b)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,232 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+// errBrowserGone is returned once Chrome died more often than the restart
+// limit allows.
+var errBrowserGone = errors.New("browser died")
+
+// browser owns the shared Chrome process that all tabs are opened in, or the
+// connection to a remote one. If Chrome dies mid-run (e.g. killed by the OOM
+// killer) or the connection is lost it can be started or connected to again
+// with restart, up to restartLimit times. To keep Chrome from leaking
+// memory over long runs it is also recycled after recycleAfter tabs or once
+// it uses more than maxRSS, and killed if it stops responding so it gets
+// restarted.
+type browser struct {
+	log          *slog.Logger
+	opts         []chromedp.ExecAllocatorOption
+	remote       string // DevTools URL of a remote browser, if set opts are unused
+	restartLimit int
+	recycleAfter int
+	maxRSS       int64
+
+	mu         sync.Mutex
+	cond       *sync.Cond // signalled when a tab is released
+	ctx        context.Context
+	cancel     context.CancelFunc
+	execCancel context.CancelFunc
+	restarts   int
+	active     int // tabs currently open
+	used       int // tabs opened since the last (re)start
+	// recycle is set once Chrome uses more memory than maxRSS
+	recycle bool
+
+	done chan struct{}
+}
+
+func newBrowser(allocOpts []chromedp.ExecAllocatorOption, opts Options) (*browser, error) {
+	b := &browser{
+		log:          opts.Logger,
+		opts:         allocOpts,
+		remote:       opts.Remote,
+		restartLimit: opts.RestartLimit,
+		recycleAfter: opts.RecycleAfter,
+		maxRSS:       opts.MaxRSS,
+		done:         make(chan struct{}),
+	}
+	b.cond = sync.NewCond(&b.mu)
+	if err := b.start(); err != nil {
+		return nil, err
+	}
+	if opts.HealthCheckInterval > 0 {
+		go b.watch(opts.HealthCheckInterval)
+	}
+	return b, nil
+}
+
+// start launches a new Chrome process or connects to the remote one. b.mu must
+// be held or b must not be shared yet.
+func (b *browser) start() error {
+	var allocCtx context.Context
+	var execCancel context.CancelFunc
+	if b.remote != "" {
+		// without a /devtools/browser/ path the URL is looked up again
+		// every time, so restarts of the remote browser are picked up
+		allocCtx, execCancel = chromedp.NewRemoteAllocator(context.Background(), b.remote)
+	} else {
+		allocCtx, execCancel = chromedp.NewExecAllocator(context.Background(), b.opts...)
+	}
+	ctx, cancel := chromedp.NewContext(allocCtx)
+
+	// start the browser to ensure we end up making new tabs in an
+	// existing browser instead of making a new browser each time.
+	// see: https://godoc.org/github.com/chromedp/chromedp#NewContext
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		execCancel()
+		return err
+	}
+
+	b.ctx, b.cancel, b.execCancel = ctx, cancel, execCancel
+	b.used, b.recycle = 0, false
+	return nil
+}
+
+// acquire returns the parent context a new tab should be created from. If the
+// browser is due to be recycled it waits for the open tabs to be closed and
+// restarts it first. release must be called once the tab is closed.
+func (b *browser) acquire() (context.Context, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for b.recycle || (b.recycleAfter > 0 && b.used >= b.recycleAfter) {
+		if b.active > 0 {
+			b.cond.Wait()
+			continue
+		}
+		b.log.Info("recycling browser", "pages", b.used, "over_memory", b.recycle)
+		b.cancel()
+		b.execCancel()
+		if err := b.start(); err != nil {
+			return nil, fmt.Errorf("recycling browser: %w", err)
+		}
+	}
+	b.active++
+	b.used++
+	return b.ctx, nil
+}
+
+func (b *browser) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active--
+	b.cond.Broadcast()
+}
+
+// alive reports whether the browser behind ctx is still connected. chromedp
+// cancels the browser context as soon as the websocket connection is lost.
+func alive(ctx context.Context) bool {
+	return ctx.Err() == nil
+}
+
+// restart replaces the browser behind dead with a new one. If another worker
+// already restarted it, restart returns immediately so only one new browser is
+// spawned per crash.
+func (b *browser) restart(dead context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ctx != dead && alive(b.ctx) {
+		return nil
+	}
+	if b.restarts >= b.restartLimit {
+		return fmt.Errorf("%w and restart limit of %d reached", errBrowserGone, b.restartLimit)
+	}
+	b.restarts++
+	b.log.Warn("browser died, restarting", "restart", b.restarts, "limit", b.restartLimit)
+
+	b.cancel()
+	b.execCancel()
+	return b.start()
+}
+
+// watch checks every interval that Chrome still answers. A browser that
+// hangs is killed, which makes the running captures fail and restart it. One
+// using more memory than maxRSS is recycled once its open tabs are done.
+func (b *browser) watch(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-t.C:
+		}
+
+		b.mu.Lock()
+		ctx, execCancel := b.ctx, b.execCancel
+		b.mu.Unlock()
+		if !alive(ctx) {
+			continue
+		}
+
+		hctx, cancel := context.WithTimeout(ctx, interval)
+		err := chromedp.Run(hctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			_, _, _, _, _, err := cdpbrowser.GetVersion().Do(ctx)
+			return err
+		}))
+		cancel()
+		if err != nil && alive(ctx) {
+			b.log.Warn("browser not responding, killing it", "err", err)
+			execCancel()
+			continue
+		}
+		if b.maxRSS > 0 {
+			b.checkMemory(ctx)
+		}
+	}
+}
+
+// checkMemory marks the browser behind ctx for recycling if it uses more
+// memory than maxRSS.
+func (b *browser) checkMemory(ctx context.Context) {
+	proc := chromedp.FromContext(ctx).Browser.Process()
+	if proc == nil {
+		// remote browsers are not ours to measure
+		return
+	}
+	rss, err := processTreeRSS(proc.Pid)
+	if err != nil {
+		b.log.Warn("measuring browser memory", "err", err)
+		return
+	}
+	if rss <= b.maxRSS {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ctx == ctx && !b.recycle {
+		b.log.Warn("browser over memory budget, recycling it", "rss", rss, "max", b.maxRSS)
+		b.recycle = true
+	}
+}
+
+func (b *browser) close() {
+	close(b.done)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cancel()
+	b.execCancel()
+}
+
+// BrowserVersion returns the product name and version of Chrome, e.g.
+// HeadlessChrome/126.0.6478.126.
+func (c *Capturer) BrowserVersion(ctx context.Context) (string, error) {
+	c.browser.mu.Lock()
+	bctx := c.browser.ctx
+	c.browser.mu.Unlock()
+	_, product, _, _, _, err := cdpbrowser.GetVersion().Do(cdp.WithExecutor(ctx, chromedp.FromContext(bctx).Browser))
+	return product, err
+}
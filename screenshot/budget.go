@@ -0,0 +1,159 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// errOverBudget is returned for pages going over MaxPageBytes or MaxRequests.
+var errOverBudget = errors.New("page over budget")
+
+// Budgets a page can go over, as set in Result.OverBudget.
+const (
+	OverBudgetBytes    = "bytes"
+	OverBudgetRequests = "requests"
+)
+
+// budgetRecorder counts what a tab loads against MaxPageBytes and
+// MaxRequests. Once it goes over either the page is stopped, later requests
+// fail and the steps run with bound give up. It is safe for concurrent use.
+type budgetRecorder struct {
+	maxBytes    int64
+	maxRequests int
+	exceeded    chan struct{}
+
+	mu       sync.Mutex
+	bytes    map[network.RequestID]int64
+	total    int64
+	requests int
+	over     string
+}
+
+// recordBudget starts counting the bytes loaded by the tab behind ctx, nil
+// without MaxPageBytes or MaxRequests. Requests are counted by
+// handleRequests as they are paused.
+func (c *Capturer) recordBudget(ctx context.Context) *budgetRecorder {
+	if c.opts.MaxPageBytes <= 0 && c.opts.MaxRequests <= 0 {
+		return nil
+	}
+	b := &budgetRecorder{
+		maxBytes:    c.opts.MaxPageBytes,
+		maxRequests: c.opts.MaxRequests,
+		exceeded:    make(chan struct{}),
+		bytes:       map[network.RequestID]int64{},
+	}
+	go func() {
+		// stops endless responses and whatever else is loading
+		select {
+		case <-b.exceeded:
+		case <-ctx.Done():
+			return
+		}
+		t := chromedp.FromContext(ctx).Target
+		_ = page.StopLoading().Do(cdp.WithExecutor(ctx, t))
+	}()
+	if b.maxBytes <= 0 {
+		return b
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		switch ev := ev.(type) {
+		case *network.EventDataReceived:
+			n := ev.EncodedDataLength
+			if n == 0 {
+				n = ev.DataLength
+			}
+			b.bytes[ev.RequestID] += n
+			b.total += n
+		case *network.EventLoadingFinished:
+			// the chunks do not always add up to everything received
+			if n := int64(ev.EncodedDataLength); n > b.bytes[ev.RequestID] {
+				b.total += n - b.bytes[ev.RequestID]
+				b.bytes[ev.RequestID] = n
+			}
+		default:
+			return
+		}
+		if b.total > b.maxBytes {
+			b.exceed(OverBudgetBytes)
+		}
+	})
+	return b
+}
+
+// exceed records the budget gone over first. b.mu must be held.
+func (b *budgetRecorder) exceed(budget string) {
+	if b.over != "" {
+		return
+	}
+	b.over = budget
+	close(b.exceeded)
+}
+
+// request counts a request of the tab, reporting whether it is still within
+// MaxRequests.
+func (b *budgetRecorder) request() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.over != "" {
+		return false
+	}
+	b.requests++
+	if b.maxRequests > 0 && b.requests > b.maxRequests {
+		b.exceed(OverBudgetRequests)
+		return false
+	}
+	return true
+}
+
+// err returns the error of a page over its budget, recording which in res.
+func (b *budgetRecorder) err(res *Result) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	res.OverBudget = b.over
+	switch b.over {
+	case OverBudgetBytes:
+		return fmt.Errorf("%w: loaded more than %d bytes", errOverBudget, b.maxBytes)
+	case OverBudgetRequests:
+		return fmt.Errorf("%w: made more than %d requests", errOverBudget, b.maxRequests)
+	}
+	return nil
+}
+
+// bound runs action until the page goes over its budget, so a page that would
+// keep loading does not hold the tab until Timeout.
+func (b *budgetRecorder) bound(res *Result, action chromedp.Action) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if b == nil {
+			return action.Do(ctx)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-b.exceeded:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		err := action.Do(ctx)
+		if berr := b.err(res); berr != nil {
+			return berr
+		}
+		return err
+	})
+}
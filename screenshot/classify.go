@@ -0,0 +1,108 @@
+package screenshot
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Page types set by Classify.
+const (
+	PageBasicAuth      = "basic-auth"
+	PageSSO            = "sso"
+	PageLogin          = "login"
+	PageDefaultInstall = "default-install"
+)
+
+// ssoHosts are identity providers pages redirect to for single sign-on,
+// including their subdomains.
+var ssoHosts = []string{
+	"login.microsoftonline.com",
+	"login.live.com",
+	"accounts.google.com",
+	"okta.com",
+	"oktapreview.com",
+	"auth0.com",
+	"onelogin.com",
+	"pingidentity.com",
+	"duosecurity.com",
+	"jumpcloud.com",
+	"amazoncognito.com",
+}
+
+// ssoPath matches the paths and query parameters of SSO endpoints hosted
+// anywhere, like ADFS, Keycloak, CAS and SAML or OpenID Connect requests.
+var ssoPath = regexp.MustCompile(`(?i)/adfs/ls|/protocol/openid-connect/auth|/cas/login|/saml2?/|[?&](SAMLRequest|response_type)=`)
+
+// defaultInstallTitles match the titles of the pages web servers and
+// applications show until something is set up.
+var defaultInstallTitles = regexp.MustCompile(`(?i)^(welcome to nginx!?|apache2 \w+ default page.*|test page for (the )?apache.*|it works!?|iis windows server|iis\d* welcome|internet information services|welcome to centos|apache tomcat/[\d.]+|welcome to jboss.*|welcome to wildfly.*|default web site page|default parallels plesk panel page|web server's default page|welcome to openresty!?|coming soon)$`)
+
+// loginPageJS looks for login forms.
+const loginPageJS = `(() => {
+	const visible = el => {
+		const r = el.getBoundingClientRect();
+		return r.width > 0 && r.height > 0;
+	};
+	const passwords = Array.from(document.querySelectorAll('input[type=password]')).filter(visible).length;
+	const words = /log ?in|sign ?in|log ?on|anmelden|connexion|iniciar sesi|accedi|inloggen/i;
+	const forms = Array.from(document.forms).some(f =>
+		words.test((f.action || '') + ' ' + f.id + ' ' + f.className) &&
+		f.querySelector('input[type=email], input[type=text], input:not([type])'));
+	return passwords > 0 || forms;
+})()`
+
+// detectLogin stores whether the page has a login form in res if Classify is
+// set.
+func (c *Capturer) detectLogin(res *bool) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.Classify {
+			return nil
+		}
+		return chromedp.Evaluate(loginPageJS, res).Do(ctx)
+	})
+}
+
+// classify returns the type of the captured page in res, or "" if it is none
+// of the Page* types. resp is the response of the main document, if any.
+func classify(res *Result, resp *network.Response, login bool) string {
+	if resp != nil && resp.Status == 401 {
+		auth := strings.ToLower(headerValue(resp.Headers, "WWW-Authenticate"))
+		if strings.HasPrefix(auth, "basic") || strings.HasPrefix(auth, "digest") || strings.HasPrefix(auth, "ntlm") || strings.HasPrefix(auth, "negotiate") {
+			return PageBasicAuth
+		}
+	}
+	if isSSO(res.FinalURL) {
+		return PageSSO
+	}
+	for _, r := range res.Redirects {
+		if isSSO(r.To) {
+			return PageSSO
+		}
+	}
+	if login {
+		return PageLogin
+	}
+	if defaultInstallTitles.MatchString(strings.TrimSpace(res.Title)) {
+		return PageDefaultInstall
+	}
+	return ""
+}
+
+func isSSO(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	for _, h := range ssoHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return ssoPath.MatchString(u.RequestURI())
+}
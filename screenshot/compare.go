@@ -0,0 +1,89 @@
+package screenshot
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+)
+
+// Comparison is how much two screenshots differ.
+type Comparison struct {
+	// Pixels is the fraction (0-1) of pixels that differ. If the sizes
+	// differ, the pixels only in one of the images count as differing.
+	Pixels float64
+	// Distance is the Hamming distance of the perceptual hashes (0-64).
+	Distance int
+	// Diff shows the differing pixels in red over a faded copy of a.
+	Diff *image.RGBA
+}
+
+// pixelTolerance is how much (0-0xffff) a color channel may differ for two
+// pixels to still count as the same, to ignore encoding noise.
+const pixelTolerance = 0x0800
+
+// Compare decodes and compares two encoded screenshots.
+func Compare(a, b []byte) (Comparison, error) {
+	imgA, _, err := image.Decode(bytes.NewReader(a))
+	if err != nil {
+		return Comparison{}, err
+	}
+	imgB, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return Comparison{}, err
+	}
+	hashA, err := DHash(a)
+	if err != nil {
+		return Comparison{}, err
+	}
+	hashB, err := DHash(b)
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	ba, bb := imgA.Bounds(), imgB.Bounds()
+	w, h := max(ba.Dx(), bb.Dx()), max(ba.Dy(), bb.Dy())
+	diff := image.NewRGBA(image.Rect(0, 0, w, h))
+	red := color.RGBA{R: 0xff, A: 0xff}
+	var differing int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			pa := image.Pt(ba.Min.X+x, ba.Min.Y+y)
+			pb := image.Pt(bb.Min.X+x, bb.Min.Y+y)
+			if !pa.In(ba) || !pb.In(bb) {
+				differing++
+				diff.SetRGBA(x, y, red)
+				continue
+			}
+			ca, cb := imgA.At(pa.X, pa.Y), imgB.At(pb.X, pb.Y)
+			if !sameColor(ca, cb) {
+				differing++
+				diff.SetRGBA(x, y, red)
+				continue
+			}
+			// fade the unchanged parts so the changes stand out
+			l := color.GrayModel.Convert(ca).(color.Gray).Y
+			l = 0xc0 + l/4
+			diff.SetRGBA(x, y, color.RGBA{R: l, G: l, B: l, A: 0xff})
+		}
+	}
+
+	c := Comparison{Distance: HammingDistance(hashA, hashB), Diff: diff}
+	if w*h > 0 {
+		c.Pixels = float64(differing) / float64(w*h)
+	}
+	return c, nil
+}
+
+func sameColor(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return absDiff(ar, br) <= pixelTolerance && absDiff(ag, bg) <= pixelTolerance &&
+		absDiff(ab, bb) <= pixelTolerance && absDiff(aa, ba) <= pixelTolerance
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,122 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// ConsoleMessage is a console API call or an uncaught exception of a page.
+type ConsoleMessage struct {
+	Time time.Time
+	// Level is the console method called, e.g. log, warning or error, or
+	// exception for uncaught exceptions.
+	Level string
+	Text  string
+	// URL and Line (1-based) of the script the message came from, if known.
+	URL  string
+	Line int64
+}
+
+// String formats m as a line of a console log.
+func (m ConsoleMessage) String() string {
+	s := fmt.Sprintf("%s [%s] %s", m.Time.Format(time.RFC3339Nano), m.Level, m.Text)
+	if m.URL != "" {
+		s += fmt.Sprintf(" (%s:%d)", m.URL, m.Line)
+	}
+	return s
+}
+
+// isError reports whether m is a console error or an uncaught exception.
+func (m ConsoleMessage) isError() bool {
+	return m.Level == string(runtime.APITypeError) || m.Level == "exception"
+}
+
+// consoleRecorder collects the console messages of a tab.
+type consoleRecorder struct {
+	mu       sync.Mutex
+	messages []ConsoleMessage
+}
+
+// recordConsole starts recording the console messages of the tab behind ctx.
+func recordConsole(ctx context.Context) *consoleRecorder {
+	r := &consoleRecorder{}
+	chromedp.ListenTarget(ctx, r.handle)
+	return r
+}
+
+func (r *consoleRecorder) handle(ev interface{}) {
+	var m ConsoleMessage
+	switch ev := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		args := make([]string, 0, len(ev.Args))
+		for _, arg := range ev.Args {
+			args = append(args, remoteObjectString(arg))
+		}
+		m = ConsoleMessage{Level: string(ev.Type), Text: strings.Join(args, " ")}
+		if ev.Timestamp != nil {
+			m.Time = ev.Timestamp.Time()
+		}
+		if ev.StackTrace != nil && len(ev.StackTrace.CallFrames) > 0 {
+			f := ev.StackTrace.CallFrames[0]
+			m.URL, m.Line = f.URL, f.LineNumber+1
+		}
+	case *runtime.EventExceptionThrown:
+		d := ev.ExceptionDetails
+		m = ConsoleMessage{Level: "exception", Text: d.Text, URL: d.URL, Line: d.LineNumber + 1}
+		if d.Exception != nil && d.Exception.Description != "" {
+			m.Text = d.Exception.Description
+		}
+		if ev.Timestamp != nil {
+			m.Time = ev.Timestamp.Time()
+		}
+	default:
+		return
+	}
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, m)
+}
+
+// store copies the recorded messages and their counts into res.
+func (r *consoleRecorder) store(res *Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res.Console = append([]ConsoleMessage(nil), r.messages...)
+	res.ConsoleMessages = len(r.messages)
+	res.ConsoleErrors = 0
+	for _, m := range r.messages {
+		if m.isError() {
+			res.ConsoleErrors++
+		}
+	}
+}
+
+// remoteObjectString formats a console argument like the devtools console
+// would, roughly.
+func remoteObjectString(o *runtime.RemoteObject) string {
+	if o.UnserializableValue != "" {
+		return string(o.UnserializableValue)
+	}
+	if len(o.Value) > 0 {
+		var s string
+		if err := json.Unmarshal(o.Value, &s); err == nil {
+			return s
+		}
+		return string(o.Value)
+	}
+	if o.Description != "" {
+		return o.Description
+	}
+	return string(o.Type)
+}
@@ -0,0 +1,151 @@
+package screenshot
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Cookie is a cookie of the browser as kept in a cookie jar, in the format
+// of the DevTools protocol that browser extensions and other tools export
+// cookies in as well.
+type Cookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Domain string `json:"domain"`
+	Path   string `json:"path"`
+	// Expires is in seconds since the epoch, -1 for session cookies.
+	Expires  float64 `json:"expires"`
+	HTTPOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	SameSite string  `json:"sameSite,omitempty"`
+}
+
+// expired reports whether the cookie expired before now.
+func (ck *Cookie) expired(now time.Time) bool {
+	return ck.Expires > 0 && ck.Expires < float64(now.Unix())
+}
+
+// cookieJar holds the cookies of CookieJar and those collected from the pages
+// with CollectCookies, the latest by name, domain and path. It is safe for
+// concurrent use.
+type cookieJar struct {
+	mu      sync.Mutex
+	cookies map[[3]string]Cookie
+}
+
+func newCookieJar(cookies []Cookie) *cookieJar {
+	j := &cookieJar{cookies: map[[3]string]Cookie{}}
+	j.add(cookies...)
+	return j
+}
+
+func (j *cookieJar) add(cookies ...Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, ck := range cookies {
+		j.cookies[[3]string{ck.Name, ck.Domain, ck.Path}] = ck
+	}
+}
+
+// list returns the cookies that did not expire, by domain, name and path.
+func (j *cookieJar) list() []Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	cookies := make([]Cookie, 0, len(j.cookies))
+	for _, ck := range j.cookies {
+		if !ck.expired(now) {
+			cookies = append(cookies, ck)
+		}
+	}
+	slices.SortFunc(cookies, func(a, b Cookie) int {
+		return cmp.Or(cmp.Compare(a.Domain, b.Domain), cmp.Compare(a.Name, b.Name), cmp.Compare(a.Path, b.Path))
+	})
+	return cookies
+}
+
+// Cookies returns the cookies of CookieJar together with those the pages
+// captured so far set if CollectCookies is set, to be given as CookieJar to
+// a later run.
+func (c *Capturer) Cookies() []Cookie {
+	return c.jar.list()
+}
+
+// setJarCookies sets the cookies of the jar in the tab, those of CookieJar
+// as the pages captured so far left them. Without a CookieJar there is
+// nothing to set, cookies collected are already in the browser.
+func (c *Capturer) setJarCookies() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if len(c.opts.CookieJar) == 0 {
+			return nil
+		}
+		var params []*network.CookieParam
+		for _, ck := range c.jar.list() {
+			p := &network.CookieParam{
+				Name:     ck.Name,
+				Value:    ck.Value,
+				Domain:   ck.Domain,
+				Path:     ck.Path,
+				Secure:   ck.Secure,
+				HTTPOnly: ck.HTTPOnly,
+				SameSite: network.CookieSameSite(ck.SameSite),
+			}
+			if ck.Expires > 0 {
+				expires := cdp.TimeSinceEpoch(time.Unix(0, int64(ck.Expires*float64(time.Second))))
+				p.Expires = &expires
+			}
+			params = append(params, p)
+		}
+		if err := network.SetCookies(params).Do(ctx); err != nil {
+			return fmt.Errorf("setting cookie jar: %w", err)
+		}
+		return nil
+	})
+}
+
+// collectCookies adds the cookies of the URLs res went through to the jar if
+// CollectCookies is set, so those of a login page redirected to are kept
+// as well.
+func (c *Capturer) collectCookies(res *Result) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.CollectCookies {
+			return nil
+		}
+		urls := []string{res.URL}
+		for _, r := range res.Redirects {
+			urls = append(urls, r.To)
+		}
+		if res.FinalURL != "" {
+			urls = append(urls, res.FinalURL)
+		}
+		cookies, err := network.GetCookies().WithUrls(urls).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("collecting cookies: %w", err)
+		}
+		for _, ck := range cookies {
+			cookie := Cookie{
+				Name:     ck.Name,
+				Value:    ck.Value,
+				Domain:   ck.Domain,
+				Path:     ck.Path,
+				Expires:  ck.Expires,
+				HTTPOnly: ck.HTTPOnly,
+				Secure:   ck.Secure,
+				SameSite: string(ck.SameSite),
+			}
+			if ck.Session {
+				cookie.Expires = -1
+			}
+			c.jar.add(cookie)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// injectCSSJS adds a style tag with the given rules to the page.
+const injectCSSJS = `(css => {
+	const style = document.createElement('style');
+	style.textContent = css;
+	(document.head || document.documentElement).appendChild(style);
+})(%s)`
+
+// stylesheet returns the CSS to inject into every page: the rules ending
+// animations with Deterministic and InjectCSS followed by a rule hiding
+// HideSelectors. The hidden elements keep their space so the layout of the
+// page does not shift.
+func (c *Capturer) stylesheet() string {
+	css := c.opts.InjectCSS
+	if c.opts.Deterministic {
+		css = deterministicCSS + css
+	}
+	if len(c.opts.HideSelectors) > 0 {
+		css += "\n" + strings.Join(c.opts.HideSelectors, ",\n") + " { visibility: hidden !important; }\n"
+	}
+	return css
+}
+
+// injectCSS adds the stylesheet to the page, if there is one.
+func (c *Capturer) injectCSS() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		css := c.stylesheet()
+		if css == "" {
+			return nil
+		}
+		arg, _ := json.Marshal(css)
+		if err := chromedp.Evaluate(fmt.Sprintf(injectCSSJS, arg), nil).Do(ctx); err != nil {
+			return fmt.Errorf("injecting CSS: %w", err)
+		}
+		return nil
+	})
+}
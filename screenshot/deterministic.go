@@ -0,0 +1,107 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// deterministicTime is the time pages see with Deterministic,
+// 2024-01-01T00:00:00Z in milliseconds.
+const deterministicTime = 1704067200000
+
+// deterministicJS runs before the scripts of every document with
+// Deterministic. It stops the clock at deterministicTime, seeds Math.random
+// and sets up __screenshotFreeze, which stops intervals like those of
+// carousels and resets all media to their first frame.
+const deterministicJS = `(() => {
+	const now = %d;
+	const RealDate = Date;
+	function FrozenDate(...args) {
+		if (!new.target) {
+			return new RealDate(now).toString();
+		}
+		return new RealDate(...(args.length ? args : [now]));
+	}
+	FrozenDate.prototype = RealDate.prototype;
+	FrozenDate.now = () => now;
+	FrozenDate.parse = RealDate.parse;
+	FrozenDate.UTC = RealDate.UTC;
+	window.Date = FrozenDate;
+
+	// mulberry32 with a fixed seed
+	let seed = 0x2f6b7a1d;
+	Math.random = () => {
+		seed = (seed + 0x6d2b79f5) | 0;
+		let t = Math.imul(seed ^ (seed >>> 15), 1 | seed);
+		t = (t + Math.imul(t ^ (t >>> 7), 61 | t)) ^ t;
+		return ((t ^ (t >>> 14)) >>> 0) / 4294967296;
+	};
+
+	let frozen = false;
+	const setInterval = window.setInterval;
+	window.setInterval = function (fn, ...rest) {
+		if (typeof fn !== 'function') {
+			return setInterval.call(this, fn, ...rest);
+		}
+		return setInterval.call(this, function (...args) {
+			if (!frozen) {
+				return fn.apply(this, args);
+			}
+		}, ...rest);
+	};
+	Object.defineProperty(window, '__screenshotFreeze', {
+		value: () => {
+			frozen = true;
+			for (const media of document.querySelectorAll('video, audio')) {
+				media.autoplay = false;
+				media.pause();
+				media.currentTime = 0;
+			}
+		},
+	});
+})()`
+
+// deterministicCSS ends all CSS animations and transitions right away and
+// hides the text cursor.
+const deterministicCSS = `*, *::before, *::after {
+	animation-delay: -1ms !important;
+	animation-duration: 1ms !important;
+	animation-iteration-count: 1 !important;
+	transition-delay: 0s !important;
+	transition-duration: 0s !important;
+	caret-color: transparent !important;
+	scroll-behavior: auto !important;
+}
+`
+
+// freezeTime adds deterministicJS to every document of the tab if
+// Deterministic is set. It must run before navigating.
+func (c *Capturer) freezeTime() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.Deterministic {
+			return nil
+		}
+		_, err := page.AddScriptToEvaluateOnNewDocument(fmt.Sprintf(deterministicJS, deterministicTime)).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("freezing time: %w", err)
+		}
+		return nil
+	})
+}
+
+// freeze stops the intervals and media of the page if Deterministic is set,
+// right before it is captured.
+func (c *Capturer) freeze() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.Deterministic {
+			return nil
+		}
+		if err := chromedp.Evaluate(`window.__screenshotFreeze && window.__screenshotFreeze()`, nil).Do(ctx); err != nil {
+			return fmt.Errorf("freezing page: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,33 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+)
+
+// errDownload is returned for URLs Chrome downloads instead of rendering
+// them, like PDFs or ZIP files.
+var errDownload = errors.New("page is a download")
+
+// Download is the file a page started downloading instead of rendering.
+type Download struct {
+	URL      string `json:"url"`
+	Filename string `json:"filename,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
+}
+
+// denyDownloads makes Chrome refuse downloads in the tab's browser context,
+// reporting them so navigate can stop waiting for a page that will never
+// load.
+func denyDownloads() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		p := cdpbrowser.SetDownloadBehavior(cdpbrowser.SetDownloadBehaviorBehaviorDeny).WithEventsEnabled(true)
+		if id := chromedp.FromContext(ctx).BrowserContextID; id != "" {
+			p = p.WithBrowserContextID(id)
+		}
+		return p.Do(ctx)
+	})
+}
@@ -0,0 +1,153 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/device"
+)
+
+// lookupDevice finds a device preset by its name, e.g. "iPhone 13" or "Pixel
+// 5 landscape", ignoring case.
+func lookupDevice(name string) (device.Info, error) {
+	for d := device.BlackberryPlayBook; d <= device.MotoG4landscape; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return d.Device(), nil
+		}
+	}
+	return device.Info{}, fmt.Errorf("unknown device %q", name)
+}
+
+// emulateViewport forces the tab to render in a width x height viewport,
+// emulating the configured device if there is one.
+func (c *Capturer) emulateViewport(width, height int64) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		orientation := &emulation.ScreenOrientation{
+			Type:  emulation.OrientationTypeLandscapePrimary,
+			Angle: 0,
+		}
+		if c.device.Mobile && !c.device.Landscape {
+			orientation = &emulation.ScreenOrientation{
+				Type:  emulation.OrientationTypePortraitPrimary,
+				Angle: 0,
+			}
+		}
+		err := emulation.SetDeviceMetricsOverride(width, height, c.opts.Scale, c.device.Mobile).
+			WithScreenOrientation(orientation).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+		if c.device.Touch {
+			return emulation.SetTouchEmulationEnabled(true).Do(ctx)
+		}
+		return nil
+	})
+}
+
+// emulateUserAgent overrides the user agent if one is configured.
+func (c *Capturer) emulateUserAgent() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if c.userAgent == "" {
+			return nil
+		}
+		return emulation.SetUserAgentOverride(c.userAgent).Do(ctx)
+	})
+}
+
+// emulateMedia applies the configured media type and the dark color scheme
+// and reduced motion preferences.
+func (c *Capturer) emulateMedia() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var features []*emulation.MediaFeature
+		if c.opts.Dark {
+			features = append(features, &emulation.MediaFeature{Name: "prefers-color-scheme", Value: "dark"})
+		}
+		if c.opts.ReducedMotion {
+			features = append(features, &emulation.MediaFeature{Name: "prefers-reduced-motion", Value: "reduce"})
+		}
+		if c.opts.Media == "" && features == nil {
+			return nil
+		}
+		return emulation.SetEmulatedMedia().WithMedia(c.opts.Media).WithFeatures(features).Do(ctx)
+	})
+}
+
+// Geolocation is a position to report to pages asking for it.
+type Geolocation struct {
+	Latitude, Longitude float64
+}
+
+// emulateLocale applies the configured locale, timezone and geolocation.
+func (c *Capturer) emulateLocale() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if c.opts.Lang != "" {
+			if err := emulation.SetLocaleOverride().WithLocale(c.opts.Lang).Do(ctx); err != nil {
+				return fmt.Errorf("setting locale: %w", err)
+			}
+		}
+		if c.opts.Timezone != "" {
+			if err := emulation.SetTimezoneOverride(c.opts.Timezone).Do(ctx); err != nil {
+				return fmt.Errorf("setting timezone: %w", err)
+			}
+		}
+		if geo := c.opts.Geolocation; geo != nil {
+			err := cdpbrowser.GrantPermissions([]cdpbrowser.PermissionType{cdpbrowser.PermissionTypeGeolocation}).Do(ctx)
+			if err != nil {
+				return err
+			}
+			return emulation.SetGeolocationOverride().
+				WithLatitude(geo.Latitude).
+				WithLongitude(geo.Longitude).
+				WithAccuracy(1).
+				Do(ctx)
+		}
+		return nil
+	})
+}
+
+// Throttle is a network connection to emulate. Download and Upload are in
+// bytes per second, 0 does not limit them.
+type Throttle struct {
+	Download int64
+	Upload   int64
+	Latency  time.Duration
+}
+
+// Network connection presets, as in Chrome's DevTools.
+var (
+	// 1.44 Mbit/s down, 675 kbit/s up
+	Throttle3G = Throttle{Download: 180_000, Upload: 84_375, Latency: 562500 * time.Microsecond}
+	// 400 kbit/s both ways
+	ThrottleSlow3G = Throttle{Download: 50_000, Upload: 50_000, Latency: 2 * time.Second}
+)
+
+// emulateNetwork slows the tab's connection down to Throttle if one is
+// configured.
+func (c *Capturer) emulateNetwork() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		t := c.opts.Throttle
+		if t == nil {
+			return nil
+		}
+		// -1 disables throttling in that direction
+		download, upload := float64(t.Download), float64(t.Upload)
+		if t.Download == 0 {
+			download = -1
+		}
+		if t.Upload == 0 {
+			upload = -1
+		}
+		err := network.EmulateNetworkConditions(false, float64(t.Latency.Milliseconds()), download, upload).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("throttling network: %w", err)
+		}
+		return nil
+	})
+}
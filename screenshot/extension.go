@@ -0,0 +1,32 @@
+package screenshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// extensionFlags returns the flags loading the unpacked extensions in dirs.
+// Extensions only run in the new headless mode, which is used with them.
+func extensionFlags(dirs []string) ([]chromedp.ExecAllocatorOption, error) {
+	abs := make([]string, len(dirs))
+	for i, dir := range dirs {
+		var err error
+		if abs[i], err = filepath.Abs(dir); err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(filepath.Join(abs[i], "manifest.json")); err != nil {
+			return nil, fmt.Errorf("extension %s is not an unpacked extension: %w", dir, err)
+		}
+	}
+	list := strings.Join(abs, ",")
+	return []chromedp.ExecAllocatorOption{
+		// on by default for chromedp
+		chromedp.Flag("disable-extensions", false),
+		chromedp.Flag("disable-extensions-except", list),
+		chromedp.Flag("load-extension", list),
+	}, nil
+}
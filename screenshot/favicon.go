@@ -0,0 +1,145 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/io"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// faviconJS finds the URL of the page's icon, /favicon.ico if it does not
+// link one.
+const faviconJS = `(() => {
+	const link = document.querySelector('link[rel~="icon" i]');
+	return link && link.href ? link.href : new URL('/favicon.ico', location.href).href;
+})()`
+
+// fetchFavicon loads the icon of the page into res if Favicon is set. The
+// icon is loaded by the browser, with the page's cookies, but pages without
+// one are not an error.
+func (c *Capturer) fetchFavicon(res *Result) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.Favicon {
+			return nil
+		}
+		var iconURL string
+		if err := chromedp.Evaluate(faviconJS, &iconURL).Do(ctx); err != nil {
+			return fmt.Errorf("finding favicon: %w", err)
+		}
+		if !strings.HasPrefix(iconURL, "http://") && !strings.HasPrefix(iconURL, "https://") {
+			// data: URLs and the like have nothing to fetch
+			return nil
+		}
+
+		frameID := cdp.FrameID(chromedp.FromContext(ctx).Target.TargetID)
+		loaded, err := network.LoadNetworkResource(iconURL, &network.LoadNetworkResourceOptions{IncludeCredentials: true}).
+			WithFrameID(frameID).
+			Do(ctx)
+		if err != nil {
+			return fmt.Errorf("loading favicon: %w", err)
+		}
+		if !loaded.Success || loaded.HTTPStatusCode >= 400 || loaded.Stream == "" {
+			c.log.Debug("no favicon", "url", iconURL, "status", loaded.HTTPStatusCode, "err", loaded.NetErrorName)
+			return nil
+		}
+		data, err := readStream(ctx, loaded.Stream)
+		if err != nil {
+			return fmt.Errorf("reading favicon: %w", err)
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		res.Favicon = data
+		res.FaviconURL = iconURL
+		res.FaviconType = headerValue(loaded.Headers, "Content-Type")
+		res.FaviconHash = faviconHash(data)
+		return nil
+	})
+}
+
+// readStream reads and closes a stream handed out by the browser.
+func readStream(ctx context.Context, handle io.StreamHandle) ([]byte, error) {
+	defer io.Close(handle).Do(ctx)
+	var data []byte
+	for {
+		// ReadParams.Do drops whether the data is base64 encoded
+		var chunk io.ReadReturns
+		if err := cdp.Execute(ctx, io.CommandRead, io.Read(handle), &chunk); err != nil {
+			return nil, err
+		}
+		if chunk.Base64encoded {
+			b, err := base64.StdEncoding.DecodeString(chunk.Data)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, b...)
+		} else {
+			data = append(data, chunk.Data...)
+		}
+		if chunk.EOF {
+			return data, nil
+		}
+	}
+}
+
+// faviconHash is the hash Shodan indexes favicons by, the 32 bit MurmurHash3
+// of the base64 encoded icon with a newline after every 76 characters, as
+// Python's base64.encodebytes writes it.
+func faviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteByte('\n')
+	return int32(murmur3([]byte(b.String()), 0))
+}
+
+// murmur3 is the x86 32 bit MurmurHash3 of data.
+func murmur3(data []byte, seed uint32) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	h := seed
+	n := len(data) / 4 * 4
+	for i := 0; i < n; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	switch tail := data[n:]; len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
@@ -0,0 +1,30 @@
+package screenshot
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// errFileURL is returned for file:// URLs without AllowFiles.
+var errFileURL = errors.New("a local file, only captured with AllowFiles")
+
+func isFileURL(requestURL string) bool {
+	return len(requestURL) >= len("file://") && strings.EqualFold(requestURL[:len("file://")], "file://")
+}
+
+// FileURL returns the file:// URL of the local file at path, e.g. a saved
+// page to capture with AllowFiles.
+func FileURL(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+	if !strings.HasPrefix(u.Path, "/") {
+		// C:/... on Windows
+		u.Path = "/" + u.Path
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,201 @@
+package screenshot
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"golang.org/x/net/idna"
+)
+
+// maxNameLength is the most bytes of a file or directory name given by
+// Filepath and Namer, below the 255 most file systems allow with room for
+// the extensions and suffixes added to it.
+const maxNameLength = 200
+
+// Filepath returns the path, without extension, a screenshot of requestURL
+// is saved to under the directory prefix. The name ends in a hash of the
+// whole URL, so URLs only differing in their scheme, query, case or
+// characters not kept in the name get their own files. Names are safe on
+// Windows and macOS as well.
+func Filepath(prefix, requestURL string) (string, error) {
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", err
+	}
+	requestPath := u.EscapedPath()
+
+	if requestPath == "/" {
+		requestPath = "/index"
+	}
+
+	re := regexp.MustCompile("[^a-zA-Z0-9_.%-]")
+	requestPath = re.ReplaceAllString(requestPath, "-")
+
+	host := asciiHost(u.Hostname())
+	if u.Scheme == "file" {
+		host = "file"
+	}
+	name := cleanPath(fmt.Sprintf("%s-%s-%s", host, u.Port(), requestPath))
+	hash := urlHash(requestURL)
+	if keep := maxNameLength - len(hash) - 1; len(name) > keep {
+		name = name[:keep]
+	}
+	return filepath.Join(prefix, portableName(cleanPath(name+"-"+hash))), nil
+}
+
+// asciiHost returns the punycode form of internationalized host names,
+// which would otherwise lose every character that is not ASCII in a file
+// name.
+func asciiHost(host string) string {
+	if ascii, err := idna.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// limitName shortens name to maxNameLength bytes, ending in a hash of all of
+// it to keep it apart from others with the same beginning.
+func limitName(name string) string {
+	if len(name) <= maxNameLength {
+		return name
+	}
+	hash := shortHash(name)
+	return name[:maxNameLength-len(hash)-1] + "-" + hash
+}
+
+// reservedNames are the device names Windows does not allow as file names,
+// with any extension.
+var reservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// portableName returns name, a cleaned file or directory name, such that
+// Windows keeps it as is: reserved names get a leading underscore and
+// trailing dots, which Windows drops, are removed.
+func portableName(name string) string {
+	name = strings.TrimRight(name, ".")
+	base, _, _ := strings.Cut(name, ".")
+	if name == "" || reservedNames[strings.ToLower(base)] {
+		name = "_" + name
+	}
+	return name
+}
+
+// cleanPath replaces the characters not safe in file names in savePath and
+// removes repeated dashes and slashes.
+func cleanPath(savePath string) string {
+	re := regexp.MustCompile("[^a-zA-Z0-9_.%/-]")
+	savePath = re.ReplaceAllString(savePath, "-")
+	// remove multiple dashes in a row
+	re = regexp.MustCompile("-+")
+	savePath = re.ReplaceAllString(savePath, "-")
+	// remove multiple slashes in a row
+	re = regexp.MustCompile("/+")
+	savePath = re.ReplaceAllString(savePath, "/")
+	savePath = strings.TrimSuffix(savePath, "/")
+	return savePath
+}
+
+// FilenameFields are the fields available to a filename template.
+type FilenameFields struct {
+	Scheme string
+	Host   string
+	Port   string // empty if not given in the URL
+	Path   string
+	// PathHash is a short hash of the path and query, QueryHash of only the
+	// query and URLHash of the whole URL. QueryHash is empty if there is no
+	// query.
+	PathHash  string
+	QueryHash string
+	URLHash   string
+	Timestamp string // when the capture started, like 20060102T150405
+	Status    int64
+	// Tags are those of the Result, Tag the first of them or empty.
+	Tags []string
+	Tag  string
+}
+
+// Namer names saved screenshots after a text/template filled in with
+// FilenameFields, e.g. "{{.Host}}_{{.Port}}_{{.PathHash}}". Slashes in the
+// result create subdirectories.
+type Namer struct {
+	tmpl *template.Template
+}
+
+// NewNamer parses the filename template text.
+func NewNamer(text string) (*Namer, error) {
+	tmpl, err := template.New("filename").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filename template: %w", err)
+	}
+	return &Namer{tmpl: tmpl}, nil
+}
+
+// Filepath returns the path, without extension, the screenshot in res is
+// saved to under the directory prefix. A nil Namer uses the package level
+// Filepath.
+func (n *Namer) Filepath(prefix string, res *Result) (string, error) {
+	if n == nil {
+		return Filepath(prefix, res.URL)
+	}
+	u, err := url.Parse(res.URL)
+	if err != nil {
+		return "", err
+	}
+	fields := FilenameFields{
+		Scheme:   u.Scheme,
+		Host:     asciiHost(u.Hostname()),
+		Port:     u.Port(),
+		Path:     strings.Trim(u.EscapedPath(), "/"),
+		PathHash: shortHash(u.EscapedPath() + "?" + u.RawQuery),
+		URLHash:  urlHash(res.URL),
+		Status:   res.Status,
+		Tags:     res.Tags,
+	}
+	if len(res.Tags) > 0 {
+		fields.Tag = res.Tags[0]
+	}
+	if u.RawQuery != "" {
+		fields.QueryHash = shortHash(u.RawQuery)
+	}
+	if !res.Started.IsZero() {
+		fields.Timestamp = res.Started.Format("20060102T150405")
+	}
+
+	var b strings.Builder
+	if err := n.tmpl.Execute(&b, fields); err != nil {
+		return "", err
+	}
+	// keep the templated path inside prefix, also on Windows where
+	// backslashes separate directories too
+	parts := []string{prefix}
+	for _, part := range strings.FieldsFunc(b.String(), func(r rune) bool { return r == '/' || r == '\\' }) {
+		part = cleanPath(part)
+		if part != "" && part != "." && part != ".." {
+			parts = append(parts, portableName(limitName(part)))
+		}
+	}
+	if len(parts) == 1 {
+		return "", fmt.Errorf("filename template gives an empty name for %s", res.URL)
+	}
+	return filepath.Join(parts...), nil
+}
+
+func shortHash(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:4])
+}
+
+// urlHash is longer than shortHash, it tells apart every URL of a run.
+func urlHash(requestURL string) string {
+	sum := sha1.Sum([]byte(requestURL))
+	return hex.EncodeToString(sum[:6])
+}
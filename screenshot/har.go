@@ -0,0 +1,358 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// HAR is an HTTP Archive (version 1.2) of the traffic of a single page.
+//
+// See: http://www.softwareishard.com/blog/har-12-spec/
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of a HAR.
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Pages   []HARPage   `json:"pages"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+// HARCreator names the application that created the HAR.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage is a page all entries belong to.
+type HARPage struct {
+	StartedDateTime time.Time      `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings are the page load timings, -1 if unknown.
+type HARPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// HAREntry is a single request and its response.
+type HAREntry struct {
+	PageRef         string      `json:"pageref"`
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+// HARRequest is the request of an entry.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse is the response of an entry.
+type HARResponse struct {
+	Status      int64          `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARCookie is a cookie sent or set by an entry.
+type HARCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARNameValue is a header or query string parameter.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is the body of a request.
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent describes the body of a response.
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// HARTimings are the timings of an entry in milliseconds, -1 if they do not
+// apply.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// harRecorder builds HAR entries from the network events of a tab.
+type harRecorder struct {
+	started time.Time
+
+	mu      sync.Mutex
+	entries []*HAREntry
+	// pending are the entries still loading, with the monotonic time they
+	// started at
+	pending map[network.RequestID]*HAREntry
+	starts  map[network.RequestID]time.Time
+}
+
+// recordHAR starts recording the traffic of the tab behind ctx.
+func recordHAR(ctx context.Context) *harRecorder {
+	r := &harRecorder{
+		started: time.Now(),
+		pending: map[network.RequestID]*HAREntry{},
+		starts:  map[network.RequestID]time.Time{},
+	}
+	chromedp.ListenTarget(ctx, r.handle)
+	return r
+}
+
+func (r *harRecorder) handle(ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if e := r.pending[ev.RequestID]; e != nil && ev.RedirectResponse != nil {
+			// redirects reuse the request ID of the request they are
+			// a response to
+			setHARResponse(e, ev.RedirectResponse)
+			e.Response.RedirectURL = ev.Request.URL
+			r.finish(ev.RequestID, ev.Timestamp)
+		}
+		r.pending[ev.RequestID] = newHAREntry(ev)
+		r.starts[ev.RequestID] = time.Now()
+		if ev.Timestamp != nil {
+			r.starts[ev.RequestID] = ev.Timestamp.Time()
+		}
+	case *network.EventResponseReceived:
+		if e := r.pending[ev.RequestID]; e != nil {
+			setHARResponse(e, ev.Response)
+		}
+	case *network.EventLoadingFinished:
+		if e := r.pending[ev.RequestID]; e != nil {
+			e.Response.BodySize = int64(ev.EncodedDataLength)
+			e.Response.Content.Size = int64(ev.EncodedDataLength)
+			r.finish(ev.RequestID, ev.Timestamp)
+		}
+	case *network.EventLoadingFailed:
+		if e := r.pending[ev.RequestID]; e != nil {
+			e.Error = ev.ErrorText
+			r.finish(ev.RequestID, ev.Timestamp)
+		}
+	}
+}
+
+// finish moves the pending entry of id to the finished entries. r.mu must be
+// held.
+func (r *harRecorder) finish(id network.RequestID, ts *cdp.MonotonicTime) {
+	e := r.pending[id]
+	if ts != nil {
+		e.Time = float64(ts.Time().Sub(r.starts[id])) / float64(time.Millisecond)
+		if e.Time < 0 {
+			e.Time = 0
+		}
+	}
+	// whatever is left after the request was answered is receiving the body
+	t := e.Timings
+	receive := e.Time - max(t.Blocked, 0) - max(t.DNS, 0) - max(t.Connect, 0) - t.Send - t.Wait
+	e.Timings.Receive = max(receive, 0)
+
+	r.entries = append(r.entries, e)
+	delete(r.pending, id)
+	delete(r.starts, id)
+}
+
+// har returns the recorded traffic, including requests that never finished.
+func (r *harRecorder) har(title string) *HAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := append([]*HAREntry(nil), r.entries...)
+	for _, e := range r.pending {
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "screenshot", Version: "1"},
+		Pages: []HARPage{{
+			StartedDateTime: r.started,
+			ID:              "page_1",
+			Title:           title,
+			PageTimings:     HARPageTimings{OnContentLoad: -1, OnLoad: -1},
+		}},
+		Entries: entries,
+	}}
+}
+
+func newHAREntry(ev *network.EventRequestWillBeSent) *HAREntry {
+	req := ev.Request
+	started := time.Now()
+	if ev.WallTime != nil {
+		started = ev.WallTime.Time()
+	}
+	e := &HAREntry{
+		PageRef:         "page_1",
+		StartedDateTime: started,
+		ResourceType:    ev.Type.String(),
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         req.URL + req.URLFragment,
+			Cookies:     []HARCookie{},
+			Headers:     harHeaders(req.Headers),
+			QueryString: []HARNameValue{},
+			HeadersSize: -1,
+		},
+		Response: HARResponse{
+			Cookies:     []HARCookie{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+
+	if u, err := url.Parse(req.URL); err == nil {
+		for name, values := range u.Query() {
+			for _, v := range values {
+				e.Request.QueryString = append(e.Request.QueryString, HARNameValue{Name: name, Value: v})
+			}
+		}
+	}
+
+	if req.HasPostData {
+		var body strings.Builder
+		for _, entry := range req.PostDataEntries {
+			data, _ := base64.StdEncoding.DecodeString(entry.Bytes)
+			body.Write(data)
+		}
+		e.Request.PostData = &HARPostData{
+			MimeType: headerValue(req.Headers, "Content-Type"),
+			Text:     body.String(),
+		}
+		e.Request.BodySize = int64(body.Len())
+	}
+	return e
+}
+
+func setHARResponse(e *HAREntry, resp *network.Response) {
+	version := harHTTPVersion(resp.Protocol)
+	e.Request.HTTPVersion = version
+	if resp.RequestHeaders != nil {
+		// what was actually sent, including cookies etc.
+		e.Request.Headers = harHeaders(resp.RequestHeaders)
+	}
+	e.Response.Status = resp.Status
+	e.Response.StatusText = resp.StatusText
+	e.Response.HTTPVersion = version
+	e.Response.Headers = harHeaders(resp.Headers)
+	e.Response.Content.MimeType = resp.MimeType
+	e.ServerIPAddress = resp.RemoteIPAddress
+
+	if t := resp.Timing; t != nil {
+		e.Timings.Blocked = harSpan(0, firstPositive(t.DNSStart, t.ConnectStart, t.SendStart))
+		e.Timings.DNS = harSpan(t.DNSStart, t.DNSEnd)
+		e.Timings.Connect = harSpan(t.ConnectStart, t.ConnectEnd)
+		e.Timings.SSL = harSpan(t.SslStart, t.SslEnd)
+		e.Timings.Send = max(harSpan(t.SendStart, t.SendEnd), 0)
+		e.Timings.Wait = max(harSpan(t.SendEnd, t.ReceiveHeadersEnd), 0)
+	}
+}
+
+// harSpan returns end-start, or -1 if the phase did not happen, which Chrome
+// marks with negative times.
+func harSpan(start, end float64) float64 {
+	if start < 0 || end < 0 {
+		return -1
+	}
+	return end - start
+}
+
+func firstPositive(times ...float64) float64 {
+	for _, t := range times {
+		if t >= 0 {
+			return t
+		}
+	}
+	return -1
+}
+
+func harHTTPVersion(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "":
+		return ""
+	case "h2":
+		return "HTTP/2"
+	case "h3":
+		return "HTTP/3"
+	default:
+		return strings.ToUpper(protocol)
+	}
+}
+
+func harHeaders(headers network.Headers) []HARNameValue {
+	values := make([]HARNameValue, 0, len(headers))
+	for name, v := range headers {
+		values = append(values, HARNameValue{Name: name, Value: fmt.Sprint(v)})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+	return values
+}
+
+// headerValue looks up a header case insensitively.
+func headerValue(headers network.Headers, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
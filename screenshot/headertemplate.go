@@ -0,0 +1,91 @@
+package screenshot
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+// headerPlaceholder matches the placeholders of HeaderTemplates.
+var headerPlaceholder = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// headerPlaceholders are the values of the placeholders for a request to
+// host, the number of the request in the run being seq.
+var headerPlaceholders = map[string]func(host string, seq int64) string{
+	// ip is a random IPv4 address, e.g. for X-Forwarded-For rotation
+	"ip": func(string, int64) string {
+		n := rand.Uint32()
+		return net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n)).String()
+	},
+	"seq": func(_ string, seq int64) string {
+		return strconv.FormatInt(seq, 10)
+	},
+	"host": func(host string, _ int64) string {
+		return host
+	},
+}
+
+// headerTemplates fills in the HeaderTemplates of every paused request.
+type headerTemplates struct {
+	templates map[string]string
+	seq       atomic.Int64
+}
+
+func newHeaderTemplates(templates map[string]string) (*headerTemplates, error) {
+	if len(templates) == 0 {
+		return nil, nil
+	}
+	for name, value := range templates {
+		for _, m := range headerPlaceholder.FindAllStringSubmatch(value, -1) {
+			if _, ok := headerPlaceholders[m[1]]; !ok {
+				return nil, fmt.Errorf("unknown placeholder %s in header %s, must be one of {{ip}}, {{seq}} or {{host}}", m[0], name)
+			}
+		}
+	}
+	return &headerTemplates{templates: templates}, nil
+}
+
+// apply returns headers, those of a request to requestURL, with the templates
+// filled in, replacing headers of the same name. A nil t returns nil.
+func (t *headerTemplates) apply(requestURL string, headers network.Headers) network.Headers {
+	if t == nil {
+		return nil
+	}
+	seq := t.seq.Add(1)
+	host := requestHost(requestURL)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	applied := network.Headers{}
+	for name, value := range headers {
+		applied[name] = value
+	}
+	for name, value := range t.templates {
+		for existing := range applied {
+			if strings.EqualFold(existing, name) {
+				delete(applied, existing)
+			}
+		}
+		applied[name] = headerPlaceholder.ReplaceAllStringFunc(value, func(m string) string {
+			return headerPlaceholders[headerPlaceholder.FindStringSubmatch(m)[1]](host, seq)
+		})
+	}
+	return applied
+}
+
+// headerEntries returns headers as the list of headers to continue a request
+// with.
+func headerEntries(headers network.Headers) []*fetch.HeaderEntry {
+	entries := make([]*fetch.HeaderEntry, 0, len(headers))
+	for name, value := range headers {
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+	}
+	return entries
+}
@@ -0,0 +1,139 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// intercepting reports whether requests of a tab need to be paused, to
+// answer auth challenges, to block them, to enforce the scope, to override
+// the Host header, to fill in header templates, to record them or to count
+// them against MaxRequests.
+func (c *Capturer) intercepting() bool {
+	return c.answersAuth() || c.blocker != nil || c.scope != nil || c.opts.HostHeader != "" || c.templates != nil || c.opts.SaveRequests || c.opts.MaxRequests > 0
+}
+
+// answersAuth reports whether there are credentials for proxy or server
+// authentication challenges.
+func (c *Capturer) answersAuth() bool {
+	return c.proxyAuth != nil || c.opts.Credentials != nil || len(c.opts.HostCredentials) > 0
+}
+
+// enableInterception enables the fetch domain for the tab, which pauses every
+// request until handleRequests resumes it.
+func (c *Capturer) enableInterception() chromedp.Action {
+	return fetch.Enable().WithHandleAuthRequests(c.answersAuth())
+}
+
+// credentials returns the credentials for the server at rawURL, if any.
+func (c *Capturer) credentials(rawURL string) *url.Userinfo {
+	if u, err := url.Parse(rawURL); err == nil {
+		if creds, ok := c.opts.HostCredentials[strings.ToLower(u.Hostname())]; ok {
+			return creds
+		}
+	}
+	return c.opts.Credentials
+}
+
+// handleRequests resumes the paused requests of the tab behind ctx, failing
+// those matched by the blocker and documents out of scope, like a redirect to
+// a third party, and answers authentication challenges. Requests to the
+// host of pageURL get the HostHeader, if set, and every request the
+// HeaderTemplates. If requests is not nil the responses are paused as well
+// to be recorded in it. Requests of a page over its budget fail.
+func (c *Capturer) handleRequests(ctx context.Context, pageURL string, requests *requestRecorder, budget *budgetRecorder) {
+	// requests whose credentials were already given, they were wrong if
+	// asked for again. Events are handled one at a time.
+	answered := map[fetch.RequestID]bool{}
+	var pageHost string
+	if u, err := url.Parse(pageURL); err == nil {
+		pageHost = u.Host
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go func() {
+				cont := fetch.ContinueRequest(ev.RequestID)
+				var action chromedp.Action = cont
+				switch {
+				case ev.ResponseStatusCode != 0 || ev.ResponseErrorReason != "":
+					// paused again at the response stage
+					requests.record(ev)
+				case c.blocker.blocks(ev.Request.URL, ev.ResourceType):
+					action = fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+				case ev.ResourceType == network.ResourceTypeDocument && !c.scope.allows(ctx, ev.Request.URL):
+					c.log.Warn("not loading out of scope page", "url", ev.Request.URL)
+					action = fetch.FailRequest(ev.RequestID, network.ErrorReasonAccessDenied)
+				case !budget.request():
+					action = fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+				default:
+					headers := ev.Request.Headers
+					templated := c.templates.apply(ev.Request.URL, headers)
+					if templated != nil {
+						headers = templated
+					}
+					if c.opts.HostHeader != "" && requestHost(ev.Request.URL) == pageHost {
+						cont = cont.WithHeaders(withHost(headers, c.opts.HostHeader))
+					} else if templated != nil {
+						cont = cont.WithHeaders(headerEntries(headers))
+					}
+					action = cont.WithInterceptResponse(requests != nil)
+				}
+				t := chromedp.FromContext(ctx).Target
+				_ = action.Do(cdp.WithExecutor(ctx, t))
+			}()
+		case *fetch.EventAuthRequired:
+			auth := c.proxyAuth
+			if ev.AuthChallenge.Source != fetch.AuthChallengeSourceProxy {
+				auth = c.credentials(ev.Request.URL)
+			}
+			resp := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+			switch {
+			case auth != nil && answered[ev.RequestID]:
+				// shows the 401 or 407 page instead of asking forever
+				c.log.Warn("credentials rejected", "url", ev.Request.URL, "origin", ev.AuthChallenge.Origin)
+				resp = &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+			case auth != nil:
+				answered[ev.RequestID] = true
+				password, _ := auth.Password()
+				resp = &fetch.AuthChallengeResponse{
+					Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+					Username: auth.Username(),
+					Password: password,
+				}
+			}
+			go func() {
+				t := chromedp.FromContext(ctx).Target
+				_ = fetch.ContinueWithAuth(ev.RequestID, resp).Do(cdp.WithExecutor(ctx, t))
+			}()
+		}
+	})
+}
+
+func requestHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// withHost returns headers with the Host header set to host, as the full
+// list of headers to continue a request with.
+func withHost(headers network.Headers, host string) []*fetch.HeaderEntry {
+	entries := []*fetch.HeaderEntry{{Name: "Host", Value: host}}
+	for name, value := range headers {
+		if strings.EqualFold(name, "Host") {
+			continue
+		}
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+	}
+	return entries
+}
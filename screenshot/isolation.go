@@ -0,0 +1,101 @@
+package screenshot
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Isolation is how the pages captured at the same time are kept apart.
+type Isolation string
+
+// Supported isolation modes.
+const (
+	// IsolationTab opens every page in a tab of one shared Chrome process.
+	IsolationTab Isolation = "tab"
+	// IsolationBrowser gives every capture running at the same time a
+	// Chrome process of its own, so a page crashing Chrome or filling its
+	// memory only takes down its own capture and no cookies are shared
+	// between them.
+	IsolationBrowser Isolation = "browser"
+)
+
+// browserPool hands out Chrome processes with IsolationBrowser, one per
+// capture at a time. More are started as more captures run in parallel, so
+// there end up being as many as there are workers.
+type browserPool struct {
+	allocOpts []chromedp.ExecAllocatorOption
+	opts      Options
+
+	mu   sync.Mutex
+	idle []*browser
+	all  []*browser
+}
+
+func newBrowserPool(first *browser, allocOpts []chromedp.ExecAllocatorOption, opts Options) *browserPool {
+	return &browserPool{
+		allocOpts: allocOpts,
+		opts:      opts,
+		idle:      []*browser{first},
+		all:       []*browser{first},
+	}
+}
+
+// get returns an idle browser, starting a new one if there is none. put must
+// be called once the capture is done with it.
+func (p *browserPool) get() (*browser, error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		b := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return b, nil
+	}
+	p.mu.Unlock()
+
+	b, err := newBrowser(p.allocOpts, p.opts)
+	if err != nil {
+		return nil, fmt.Errorf("error starting browser: %w", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.all = append(p.all, b)
+	return b, nil
+}
+
+func (p *browserPool) put(b *browser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idle = append(p.idle, b)
+}
+
+// browsers returns every browser started so far.
+func (p *browserPool) browsers() []*browser {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*browser(nil), p.all...)
+}
+
+// acquireBrowser returns the browser the next capture should open its tab in.
+// releaseBrowser must be called with it once the capture is done.
+func (c *Capturer) acquireBrowser() (*browser, error) {
+	if c.pool == nil {
+		return c.browser, nil
+	}
+	return c.pool.get()
+}
+
+func (c *Capturer) releaseBrowser(b *browser) {
+	if c.pool != nil {
+		c.pool.put(b)
+	}
+}
+
+// browsers returns the browsers of c, only the shared one with IsolationTab.
+func (c *Capturer) browsers() []*browser {
+	if c.pool == nil {
+		return []*browser{c.browser}
+	}
+	return c.pool.browsers()
+}
@@ -0,0 +1,42 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+)
+
+// linksJS collects the absolute URLs of the links of the page that have the
+// same origin as it, without fragments and each only once.
+const linksJS = `(() => {
+	const seen = new Set();
+	for (const a of document.querySelectorAll('a[href], area[href]')) {
+		let u;
+		try {
+			u = new URL(a.href, location.href);
+		} catch (e) {
+			continue;
+		}
+		if (u.origin !== location.origin) {
+			continue;
+		}
+		u.hash = '';
+		seen.add(u.href);
+	}
+	return [...seen];
+})()`
+
+// extractLinks stores the same origin links of the page in links if Links is
+// set.
+func (c *Capturer) extractLinks(links *[]string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.Links {
+			return nil
+		}
+		if err := chromedp.Evaluate(linksJS, links).Do(ctx); err != nil {
+			return fmt.Errorf("extracting links: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,212 @@
+package screenshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const modulePath = "github.com/AlfredBerg/screenshot"
+
+// maxExifValue is the most bytes of a value written to EXIF, which has to fit
+// into a single JPEG segment of at most 64 KiB along with the others.
+const maxExifValue = 8 << 10
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// software names this module and its version, or the commit it was built
+// from, as far as the build info knows them.
+var software = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return modulePath
+	}
+	version := ""
+	if info.Main.Path == modulePath {
+		version = info.Main.Version
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && version == "(devel)" && len(s.Value) >= 12 {
+				version = s.Value[:12]
+			}
+		}
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			version = dep.Version
+		}
+	}
+	if version == "" {
+		return modulePath
+	}
+	return modulePath + " " + version
+})
+
+// embedMetadata writes where and when the screenshots in res were captured
+// into them if EmbedMetadata is set.
+func (c *Capturer) embedMetadata(res *Result) error {
+	if !c.opts.EmbedMetadata {
+		return nil
+	}
+	var err error
+	if res.Image, err = c.embedImageMetadata(res, res.Image); err != nil {
+		return err
+	}
+	for i := range res.Sizes {
+		if res.Sizes[i].Image, err = c.embedImageMetadata(res, res.Sizes[i].Image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Capturer) embedImageMetadata(res *Result, data []byte) ([]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var err error
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		data, err = withPNGText(data, [][2]string{
+			{"URL", res.URL},
+			{"Final URL", res.FinalURL},
+			{"Creation Time", res.Started.UTC().Format(time.RFC1123Z)},
+			{"Software", software()},
+		})
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		data, err = withExif(data, res)
+	default:
+		err = errors.New("unknown image format")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("embedding metadata: %w", err)
+	}
+	return data, nil
+}
+
+// withPNGText returns the PNG data with a text chunk for every keyword and
+// text pair that has a text, right after the header. Texts that are not
+// ASCII go into international text chunks as UTF-8.
+func withPNGText(data []byte, texts [][2]string) ([]byte, error) {
+	// the signature, and the header chunk with its 13 bytes of data
+	const headerEnd = 8 + 4 + 4 + 13 + 4
+	if len(data) < headerEnd || string(data[12:16]) != "IHDR" {
+		return nil, errors.New("PNG does not start with a header chunk")
+	}
+	var chunks bytes.Buffer
+	for _, kv := range texts {
+		if kv[1] == "" {
+			continue
+		}
+		typ, body := "tEXt", kv[0]+"\x00"+kv[1]
+		if !isASCII(kv[1]) {
+			// uncompressed, without language tag and translated keyword
+			typ, body = "iTXt", kv[0]+"\x00\x00\x00\x00\x00"+kv[1]
+		}
+		writePNGChunk(&chunks, typ, []byte(body))
+	}
+	out := make([]byte, 0, len(data)+chunks.Len())
+	out = append(out, data[:headerEnd]...)
+	out = append(out, chunks.Bytes()...)
+	return append(out, data[headerEnd:]...), nil
+}
+
+func writePNGChunk(w *bytes.Buffer, typ string, body []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(body)))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(body)
+	w.WriteString(typ)
+	w.Write(body)
+	binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// withExif returns the JPEG data with an EXIF segment holding the URL as the
+// image description, the final URL as the document name, the time of capture
+// in UTC and the software. It goes after the SOI marker and a JFIF segment,
+// which must come first.
+func withExif(data []byte, res *Result) ([]byte, error) {
+	pos := 2
+	if len(data) >= 6 && data[2] == 0xff && data[3] == 0xe0 {
+		pos += 2 + int(binary.BigEndian.Uint16(data[4:6]))
+		if pos > len(data) {
+			return nil, errors.New("truncated JPEG")
+		}
+	}
+
+	// IFD0 entries, which must be sorted by tag
+	entries := []struct {
+		tag   uint16
+		value string
+	}{
+		{0x010d, res.FinalURL}, // DocumentName
+		{0x010e, res.URL},      // ImageDescription
+		{0x0131, software()},   // Software
+		{0x0132, res.Started.UTC().Format("2006:01:02 15:04:05")}, // DateTime
+	}
+	n := 0
+	for _, e := range entries {
+		if e.value != "" {
+			entries[n] = e
+			n++
+		}
+	}
+	entries = entries[:n]
+
+	// the TIFF header, the IFD and then the values that do not fit into
+	// their entry
+	var tiff, values bytes.Buffer
+	order := binary.BigEndian
+	tiff.WriteString("MM\x00\x2a")
+	binary.Write(&tiff, order, uint32(8))
+	ifdEnd := 8 + 2 + 12*len(entries) + 4
+	binary.Write(&tiff, order, uint16(len(entries)))
+	for _, e := range entries {
+		value := e.value
+		if len(value) > maxExifValue {
+			value = value[:maxExifValue]
+		}
+		value += "\x00"
+		binary.Write(&tiff, order, e.tag)
+		binary.Write(&tiff, order, uint16(2)) // ASCII
+		binary.Write(&tiff, order, uint32(len(value)))
+		if len(value) <= 4 {
+			var inline [4]byte
+			copy(inline[:], value)
+			tiff.Write(inline[:])
+			continue
+		}
+		binary.Write(&tiff, order, uint32(ifdEnd+values.Len()))
+		values.WriteString(value)
+		if values.Len()%2 == 1 {
+			// values start at word boundaries
+			values.WriteByte(0)
+		}
+	}
+	binary.Write(&tiff, order, uint32(0)) // no next IFD
+	tiff.Write(values.Bytes())
+
+	var segment bytes.Buffer
+	segment.Write([]byte{0xff, 0xe1})
+	binary.Write(&segment, order, uint16(2+6+tiff.Len()))
+	segment.WriteString("Exif\x00\x00")
+	segment.Write(tiff.Bytes())
+
+	out := make([]byte, 0, len(data)+segment.Len())
+	out = append(out, data[:pos]...)
+	out = append(out, segment.Bytes()...)
+	return append(out, data[pos:]...), nil
+}
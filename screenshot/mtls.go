@@ -0,0 +1,162 @@
+package screenshot
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// certProxy is a local HTTP proxy Chrome is pointed at to present a client
+// certificate. Chrome can only pick client certificates from the system
+// store, so the proxy terminates Chrome's TLS connections with a throwaway
+// certificate, which Chrome accepts as it ignores certificate errors, and
+// makes its own to the target with the client certificate.
+//
+// Pages then report the throwaway certificate as theirs, so Result.TLS is
+// left empty.
+type certProxy struct {
+	client *tls.Config // to the targets
+	server *tls.Config // to Chrome
+	ln     net.Listener
+	srv    *http.Server
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{} // hijacked, not tracked by srv
+}
+
+// startCertProxy starts a proxy presenting cert on a random local port.
+func startCertProxy(cert tls.Certificate) (*certProxy, error) {
+	self, err := selfSignedCert()
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &certProxy{
+		client: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			// like Chrome, which is run with ignore-certificate-errors
+			InsecureSkipVerify: true,
+			NextProtos:         []string{"http/1.1"},
+		},
+		server: &tls.Config{
+			Certificates: []tls.Certificate{self},
+			// the connection to the target only speaks HTTP/1.1
+			NextProtos: []string{"http/1.1"},
+		},
+		ln:    ln,
+		conns: map[net.Conn]struct{}{},
+	}
+	p.srv = &http.Server{Handler: p}
+	go p.srv.Serve(ln)
+	return p, nil
+}
+
+// addr is the proxy server to give Chrome.
+func (p *certProxy) addr() string {
+	return "http://" + p.ln.Addr().String()
+}
+
+func (p *certProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		// plain HTTP, no certificate to present
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return
+	}
+
+	upstream, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", r.Host, p.client)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		conn.Close()
+		upstream.Close()
+		return
+	}
+	downstream := tls.Server(conn, p.server)
+	p.track(downstream, true)
+	defer p.track(downstream, false)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, downstream)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(downstream, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+	downstream.Close()
+	upstream.Close()
+	<-done
+}
+
+func (p *certProxy) track(conn net.Conn, add bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if add {
+		p.conns[conn] = struct{}{}
+	} else {
+		delete(p.conns, conn)
+	}
+}
+
+// close stops the proxy and closes the tunnels still open.
+func (p *certProxy) close() {
+	p.srv.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+}
+
+// selfSignedCert creates the throwaway certificate shown to Chrome.
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "screenshot client certificate proxy"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * 365 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
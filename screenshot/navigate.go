@@ -0,0 +1,192 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// WaitUntil is the point in the page lifecycle a navigation is considered
+// done at.
+type WaitUntil string
+
+// Supported wait strategies.
+const (
+	WaitLoad             WaitUntil = "load"
+	WaitDOMContentLoaded WaitUntil = "domcontentloaded"
+	WaitNetworkIdle      WaitUntil = "networkidle"
+)
+
+// lifecycleEvents maps the wait strategies to the Page.lifecycleEvent name
+// Chrome emits for them.
+var lifecycleEvents = map[WaitUntil]string{
+	WaitLoad:             "load",
+	WaitDOMContentLoaded: "DOMContentLoaded",
+	WaitNetworkIdle:      "networkIdle",
+}
+
+// Redirect is a hop in the redirect chain of a page. Type is http for HTTP
+// redirects, with the Status of the redirect response, or the reason Chrome
+// gives for a client side redirect, like metaTagRefresh or scriptInitiated.
+type Redirect struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Status int64  `json:"status,omitempty"`
+	Type   string `json:"type"`
+}
+
+// navigate loads urlstr in the tab and waits for the WaitUntil lifecycle
+// event. If the page redirects on the client side before that, the event is
+// waited for on the page redirected to. The response of the final document is
+// stored in resp and the redirects followed in redirects. If the URL turns out
+// to be a download, it is stored in download and errDownload returned.
+func (c *Capturer) navigate(urlstr string, resp **network.Response, redirects *[]Redirect, download **Download) chromedp.Action {
+	want := lifecycleEvents[c.opts.WaitUntil]
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		frameID := cdp.FrameID(chromedp.FromContext(ctx).Target.TargetID)
+
+		var (
+			mu        sync.Mutex
+			fired     = map[cdp.LoaderID]bool{}
+			responses = map[cdp.LoaderID]*network.Response{}
+			notify    = make(chan struct{}, 1)
+			// current is the loader of the document being waited for,
+			// reason why the frame is about to navigate away from it
+			current  cdp.LoaderID
+			frameURL = urlstr
+			reason   string
+			// MIME types of the documents by URL, for downloads
+			mimeTypes = map[string]string{}
+		)
+		wake := func() {
+			select {
+			case notify <- struct{}{}:
+			default:
+			}
+		}
+		lctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		chromedp.ListenTarget(lctx, func(ev interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch ev := ev.(type) {
+			case *page.EventLifecycleEvent:
+				if ev.FrameID == frameID && ev.Name == want {
+					fired[ev.LoaderID] = true
+					wake()
+				}
+			case *network.EventRequestWillBeSent:
+				if ev.FrameID == frameID && ev.Type == network.ResourceTypeDocument && ev.RedirectResponse != nil {
+					*redirects = append(*redirects, Redirect{
+						From:   ev.RedirectResponse.URL,
+						To:     ev.Request.URL,
+						Status: ev.RedirectResponse.Status,
+						Type:   "http",
+					})
+					wake()
+				}
+			case *network.EventResponseReceived:
+				// redirects are followed within the same loader, so
+				// the last response is the final one
+				if ev.FrameID == frameID && ev.Type == network.ResourceTypeDocument {
+					responses[ev.LoaderID] = ev.Response
+					mimeTypes[ev.Response.URL] = ev.Response.MimeType
+				}
+			case *cdpbrowser.EventDownloadWillBegin:
+				if ev.FrameID == frameID {
+					*download = &Download{URL: ev.URL, Filename: ev.SuggestedFilename, MIMEType: mimeTypes[ev.URL]}
+					wake()
+				}
+			case *page.EventFrameRequestedNavigation:
+				if ev.FrameID == frameID {
+					reason = string(ev.Reason)
+				}
+			case *page.EventFrameNavigated:
+				f := ev.Frame
+				if f.ID != frameID {
+					return
+				}
+				if current != "" && f.LoaderID != current {
+					if reason == "" {
+						reason = "client"
+					}
+					*redirects = append(*redirects, Redirect{From: frameURL, To: f.URL, Type: reason})
+					current, reason = f.LoaderID, ""
+					wake()
+				}
+				frameURL = f.URL
+			}
+		})
+
+		if err := page.SetLifecycleEventsEnabled(true).Do(ctx); err != nil {
+			return err
+		}
+		_, loaderID, errorText, err := page.Navigate(urlstr).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if errorText != "" {
+			if errorText == "net::ERR_ABORTED" {
+				// what downloads fail with, the download may be
+				// reported just after
+				select {
+				case <-notify:
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if *download != nil {
+				return fmt.Errorf("%w of %s", errDownload, (*download).Filename)
+			}
+			return fmt.Errorf("page load error %s", errorText)
+		}
+		mu.Lock()
+		current = loaderID
+		mu.Unlock()
+
+		for {
+			mu.Lock()
+			done := fired[current]
+			*resp = responses[current]
+			hops := len(*redirects)
+			dl := *download
+			mu.Unlock()
+			if dl != nil {
+				return fmt.Errorf("%w of %s", errDownload, dl.Filename)
+			}
+			if c.opts.MaxRedirects > 0 && hops > c.opts.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", c.opts.MaxRedirects)
+			}
+			if done {
+				return nil
+			}
+
+			select {
+			case <-notify:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
+
+// waitReady runs the extra waits configured once the page has loaded.
+func (c *Capturer) waitReady() chromedp.Tasks {
+	var tasks chromedp.Tasks
+	if c.opts.WaitFor != "" {
+		tasks = append(tasks, chromedp.WaitVisible(c.opts.WaitFor, chromedp.ByQuery))
+	}
+	if c.opts.Scroll {
+		tasks = append(tasks, c.scroll())
+	}
+	return tasks
+}
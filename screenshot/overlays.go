@@ -0,0 +1,120 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+)
+
+// overlayAcceptButtons are the "accept" buttons of common consent dialogs.
+// Clicking them gets rid of the dialog for good, including the scroll lock
+// some of them put on the page.
+var overlayAcceptButtons = []string{
+	"#onetrust-accept-btn-handler",                           // OneTrust
+	"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll", // Cookiebot
+	"#CybotCookiebotDialogBodyButtonAccept",
+	"#didomi-notice-agree-button",                       // Didomi
+	".qc-cmp2-summary-buttons button[mode=primary]",     // Quantcast
+	"#truste-consent-button",                            // TrustArc
+	".cmpboxbtnyes",                                     // consentmanager
+	".cc-window .cc-allow, .cc-window .cc-dismiss",      // Cookie Consent
+	"#cookie_action_close_header",                       // CookieYes / Cookie Law Info
+	".fc-cta-consent",                                   // Google Funding Choices
+	"[data-testid=uc-accept-all-button]",                // Usercentrics
+	"#axeptio_btn_acceptAll",                            // Axeptio
+	".iubenda-cs-accept-btn",                            // iubenda
+	"#cookiescript_accept",                              // CookieScript
+	"button[data-cookiebanner=accept_button]",           // Facebook
+	"#klaro .cm-btn-success, .klaro .cm-btn-accept-all", // Klaro
+}
+
+// overlaySelectors are consent dialogs, newsletter modals and chat widgets
+// that are removed from the page if still there.
+var overlaySelectors = []string{
+	// consent dialogs
+	"#onetrust-consent-sdk",
+	"#CybotCookiebotDialog",
+	"#didomi-host",
+	".qc-cmp2-container",
+	"#truste-consent-track",
+	"#cmpbox, #cmpbox2",
+	".cc-window",
+	"#cookie-law-info-bar",
+	".fc-consent-root",
+	"#usercentrics-root",
+	"#axeptio_overlay",
+	"#iubenda-cs-banner",
+	"#cookiescript_injected",
+	"[id^=sp_message_container]",
+	"#klaro",
+	"#gdpr-cookie-message",
+	".cookie-notice, #cookie-notice",
+	// chat widgets
+	"#intercom-container, .intercom-lightweight-app",
+	"#hubspot-messages-iframe-container",
+	"#drift-widget-container, #drift-frame-controller, #drift-frame-chat",
+	"iframe#launcher, #webWidget",
+	".crisp-client",
+	"#fc_frame",
+	"#tidio-chat",
+	"iframe[title*='chat widget' i]",
+	// newsletter modals
+	".klaviyo-form[role=dialog], .needsclick[role=dialog]",
+	"#mc_embed_signup_modal, .mc-modal",
+	".pum-overlay",
+}
+
+// overlayJS clicks the accept buttons and removes the overlays it is given,
+// then falls back to removing any fixed element covering a good part of the
+// viewport that talks about cookies, consent or newsletters. Scroll locks
+// left on the document are lifted.
+const overlayJS = `(async (accept, remove) => {
+	for (const sel of accept) {
+		const el = document.querySelector(sel);
+		if (el) {
+			el.click();
+		}
+	}
+	await new Promise(r => setTimeout(r, 100));
+	for (const sel of remove) {
+		document.querySelectorAll(sel).forEach(el => el.remove());
+	}
+	const words = /cookie|consent|gdpr|newsletter/i;
+	const area = window.innerWidth * window.innerHeight;
+	for (const el of document.body.querySelectorAll('*')) {
+		const style = getComputedStyle(el);
+		if (style.position !== 'fixed' && style.position !== 'sticky') {
+			continue;
+		}
+		const r = el.getBoundingClientRect();
+		if (r.width * r.height < area * 0.2 || !words.test(el.innerText || '')) {
+			continue;
+		}
+		el.remove();
+	}
+	for (const el of [document.documentElement, document.body]) {
+		if (getComputedStyle(el).overflow === 'hidden') {
+			el.style.setProperty('overflow', 'visible', 'important');
+		}
+	}
+})(%s, %s)`
+
+// dismissOverlays gets cookie banners, newsletter modals and chat widgets out of
+// the way if DismissOverlays is set.
+func (c *Capturer) dismissOverlays() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.DismissOverlays {
+			return nil
+		}
+		accept, _ := json.Marshal(overlayAcceptButtons)
+		remove, _ := json.Marshal(overlaySelectors)
+		js := fmt.Sprintf(overlayJS, accept, remove)
+		err := withTimeout(c.opts.Timeout, chromedp.Evaluate(js, nil, awaitPromise)).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("dismissing overlays: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,65 @@
+package screenshot
+
+import (
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// URLOptions override the options of a Capturer for some of the URLs it
+// captures, see With. Zero values keep the Capturer's options.
+type URLOptions struct {
+	// Width and Height replace those of the viewport.
+	Width  int64
+	Height int64
+	// Headers and Cookies are sent in addition to the Capturer's, headers
+	// of the same name replace its.
+	Headers map[string]string
+	Cookies []*http.Cookie
+	// Delay, WaitFor and HostHeader replace those of the Capturer.
+	Delay      time.Duration
+	WaitFor    string
+	HostHeader string
+	// Tags are set as the Result's Tags, e.g. to tell which program or
+	// client a URL belongs to.
+	Tags []string
+}
+
+// With returns a Capturer with o applied to c's options, sharing c's browser
+// and host delays. Only c must be closed.
+func (c *Capturer) With(o URLOptions) *Capturer {
+	d := *c
+	if o.Width > 0 {
+		d.opts.Width = o.Width
+	}
+	if o.Height > 0 {
+		d.opts.Height = o.Height
+	}
+	if len(o.Headers) > 0 {
+		d.headers = maps.Clone(c.headers)
+		if d.headers == nil {
+			d.headers = network.Headers{}
+		}
+		for k, v := range o.Headers {
+			d.headers[k] = v
+		}
+	}
+	if len(o.Cookies) > 0 {
+		d.opts.Cookies = append(append([]*http.Cookie(nil), c.opts.Cookies...), o.Cookies...)
+	}
+	if o.Delay > 0 {
+		d.opts.Delay = o.Delay
+	}
+	if o.WaitFor != "" {
+		d.opts.WaitFor = o.WaitFor
+	}
+	if o.HostHeader != "" {
+		d.opts.HostHeader = o.HostHeader
+	}
+	if len(o.Tags) > 0 {
+		d.tags = o.Tags
+	}
+	return &d
+}
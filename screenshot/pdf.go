@@ -0,0 +1,54 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// paperSizes are the supported PDF paper sizes, width and height in inches.
+var paperSizes = map[string][2]float64{
+	"letter":  {8.5, 11},
+	"legal":   {8.5, 14},
+	"tabloid": {11, 17},
+	"a3":      {11.69, 16.54},
+	"a4":      {8.27, 11.69},
+	"a5":      {5.83, 8.27},
+}
+
+// validPaper returns an error if paper is not a supported paper size.
+func validPaper(paper string) error {
+	if _, ok := paperSizes[strings.ToLower(paper)]; !ok {
+		return fmt.Errorf("unknown paper size %q, must be one of letter, legal, tabloid, a3, a4 or a5", paper)
+	}
+	return nil
+}
+
+// paperViewport returns the size in CSS pixels of a page of paper, 96 to the
+// inch like Chrome prints.
+func paperViewport(paper string) (width, height int64) {
+	size := paperSizes[strings.ToLower(paper)]
+	return int64(math.Round(size[0] * 96)), int64(math.Round(size[1] * 96))
+}
+
+// printPDF prints the page to res if PDF is set. Chrome only supports this
+// when running headless.
+func (c *Capturer) printPDF(res *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.PDF {
+			return nil
+		}
+		size := paperSizes[strings.ToLower(c.opts.PDFPaper)]
+		var err error
+		*res, _, err = page.PrintToPDF().
+			WithPrintBackground(c.opts.PDFBackground).
+			WithPaperWidth(size[0]).
+			WithPaperHeight(size[1]).
+			Do(ctx)
+		return err
+	})
+}
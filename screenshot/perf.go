@@ -0,0 +1,83 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/performance"
+	"github.com/chromedp/chromedp"
+)
+
+// Performance holds how long a page took to load and how heavy it is. Times
+// are in milliseconds since the navigation started, 0 if the page did not get
+// there before it was captured.
+type Performance struct {
+	TTFB                 int64 `json:"ttfb_ms"`
+	DOMContentLoaded     int64 `json:"dom_content_loaded_ms"`
+	Load                 int64 `json:"load_ms"`
+	FirstContentfulPaint int64 `json:"first_contentful_paint_ms,omitempty"`
+	DOMNodes             int64 `json:"dom_nodes"`
+	// TransferSize is the size of the document and all resources as sent
+	// over the network, without those from other origins that do not allow
+	// the page to see their timing.
+	TransferSize int64 `json:"transfer_size"`
+	Requests     int   `json:"requests"`
+}
+
+// perfJS reads the navigation and paint timings and the sizes of the loaded
+// resources.
+const perfJS = `(() => {
+	const nav = performance.getEntriesByType('navigation')[0] || {};
+	const fcp = performance.getEntriesByName('first-contentful-paint')[0];
+	const resources = performance.getEntriesByType('resource');
+	return {
+		ttfb_ms: Math.round(nav.responseStart || 0),
+		dom_content_loaded_ms: Math.round(nav.domContentLoadedEventEnd || 0),
+		load_ms: Math.round(nav.loadEventEnd || 0),
+		first_contentful_paint_ms: fcp ? Math.round(fcp.startTime) : 0,
+		transfer_size: resources.reduce((sum, r) => sum + (r.transferSize || 0), nav.transferSize || 0),
+		requests: resources.length + 1,
+	};
+})()`
+
+// measurePerformance stores the performance of the page in res if Performance
+// is set.
+func (c *Capturer) measurePerformance(res **Performance) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.Performance {
+			return nil
+		}
+		var perf Performance
+		if err := chromedp.Evaluate(perfJS, &perf).Do(ctx); err != nil {
+			return fmt.Errorf("measuring performance: %w", err)
+		}
+		if err := performance.Enable().Do(ctx); err != nil {
+			return fmt.Errorf("measuring performance: %w", err)
+		}
+		metrics, err := performance.GetMetrics().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("measuring performance: %w", err)
+		}
+		for _, m := range metrics {
+			if m.Name == "Nodes" {
+				perf.DOMNodes = int64(m.Value)
+			}
+		}
+		*res = &perf
+		return nil
+	})
+}
+
+// slow reports whether perf took longer than threshold to load, or to get to
+// DOMContentLoaded if it was captured before the load event.
+func slow(perf *Performance, threshold time.Duration) bool {
+	if perf == nil || threshold <= 0 {
+		return false
+	}
+	loaded := perf.Load
+	if loaded == 0 {
+		loaded = perf.DOMContentLoaded
+	}
+	return time.Duration(loaded)*time.Millisecond > threshold
+}
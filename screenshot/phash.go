@@ -0,0 +1,103 @@
+package screenshot
+
+import (
+	"bytes"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"sync"
+
+	_ "golang.org/x/image/webp"
+)
+
+// DHash computes the difference hash of an encoded screenshot. Near
+// identical images have hashes with a small Hamming distance, which makes it
+// useful to find default pages, parked domains etc. across many hosts.
+func DHash(data []byte) (uint64, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+
+	// shrink to 9x8 grayscale and compare each pixel to its right neighbour
+	const w, h = 9, 8
+	var gray [h][w]float64
+	b := img.Bounds()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cell := image.Rect(
+				b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h,
+				b.Min.X+(x+1)*b.Dx()/w, b.Min.Y+(y+1)*b.Dy()/h,
+			)
+			gray[y][x] = averageLuma(img, cell)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if gray[y][x] < gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// averageLuma returns the average brightness of r in img. Large cells are
+// sampled on a grid instead of visiting every pixel.
+func averageLuma(img image.Image, r image.Rectangle) float64 {
+	const samples = 32
+	stepX := max(r.Dx()/samples, 1)
+	stepY := max(r.Dy()/samples, 1)
+
+	var sum float64
+	var n int
+	for y := r.Min.Y; y < r.Max.Y; y += stepY {
+		for x := r.Min.X; x < r.Max.X; x += stepX {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(cr) + 0.587*float64(cg) + 0.114*float64(cb)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// HammingDistance returns the number of bits two hashes differ in.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Clusterer groups hashes that are within Threshold bits of each other. Each
+// cluster is represented by the first hash assigned to it. It is safe for
+// concurrent use.
+type Clusterer struct {
+	Threshold int
+
+	mu      sync.Mutex
+	leaders []uint64
+}
+
+// Assign returns the cluster hash belongs to, starting at 1. If it is not
+// close enough to any existing cluster a new one is started.
+func (c *Clusterer) Assign(hash uint64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	best, bestDist := 0, c.Threshold+1
+	for i, leader := range c.leaders {
+		if d := HammingDistance(hash, leader); d < bestDist {
+			best, bestDist = i+1, d
+		}
+	}
+	if best != 0 {
+		return best
+	}
+	c.leaders = append(c.leaders, hash)
+	return len(c.leaders)
+}
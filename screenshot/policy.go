@@ -0,0 +1,105 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Policy is what to do about a capture failing with an error of a category.
+type Policy string
+
+const (
+	// PolicyRetry retries the capture up to Retries times, which is what
+	// is done for categories without a policy.
+	PolicyRetry Policy = "retry"
+	// PolicyCapture takes a screenshot of the page anyway, like
+	// CaptureErrors, without retrying.
+	PolicyCapture Policy = "capture-anyway"
+	// PolicySkip and PolicyAbort are not retried and left to the caller to
+	// act on, by skipping the URL or stopping the run.
+	PolicySkip  Policy = "skip"
+	PolicyAbort Policy = "abort-run"
+)
+
+// policyCategories are the error categories a policy can be set for.
+var policyCategories = []string{
+	CategoryDNS, CategoryTLS, CategoryRefused, CategoryNetwork, CategoryTimeout, CategoryBrowser,
+	CategoryHTTPClient, CategoryHTTPServer, CategoryScope, CategoryDownload, CategoryBudget, CategoryOther,
+}
+
+func validPolicies(policies map[string]Policy) error {
+	for category, p := range policies {
+		known := false
+		for _, c := range policyCategories {
+			known = known || c == category
+		}
+		if !known {
+			return fmt.Errorf("unknown error category %q, must be one of dns, tls, connection-refused, network, timeout, browser, http-4xx, http-5xx, scope, download, budget or other", category)
+		}
+		switch p {
+		case PolicyRetry, PolicyCapture, PolicySkip, PolicyAbort:
+		default:
+			return fmt.Errorf("unknown policy %q for %s errors, must be retry, capture-anyway, skip or abort-run", p, category)
+		}
+	}
+	return nil
+}
+
+// Policy returns the policy of Policies for the category of err, "" if it has
+// none.
+func (c *Capturer) Policy(err error) Policy {
+	return c.opts.Policies[ErrorCategory(err)]
+}
+
+// capturingErrors reports whether screenshots may be taken of pages that
+// fail to load.
+func (o *Options) capturingErrors() bool {
+	if o.CaptureErrors {
+		return true
+	}
+	for _, p := range o.Policies {
+		if p == PolicyCapture {
+			return true
+		}
+	}
+	return false
+}
+
+// capturesError reports whether a screenshot is taken of a page failing
+// with err, as its policy says or else if CaptureErrors is set.
+func (c *Capturer) capturesError(err error) bool {
+	if p, ok := c.opts.Policies[ErrorCategory(err)]; ok {
+		return p == PolicyCapture
+	}
+	return c.opts.CaptureErrors
+}
+
+// statusError is the error of a page answering with an HTTP error status,
+// which only fails if Policies has a policy for its category.
+type statusError struct {
+	status int64
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("HTTP status %d", e.status)
+}
+
+// checkStatus fails the capture of a page that answered with a 4xx or 5xx
+// status if there is a policy for it. Otherwise error pages are captured like
+// any other.
+func (c *Capturer) checkStatus(resp **network.Response, res *Result) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if *resp == nil || (*resp).Status < 400 {
+			return nil
+		}
+		err := &statusError{status: (*resp).Status}
+		if _, ok := c.opts.Policies[ErrorCategory(err)]; !ok {
+			return nil
+		}
+		res.Status = (*resp).Status
+		return err
+	})
+}
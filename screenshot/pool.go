@@ -0,0 +1,24 @@
+package screenshot
+
+import (
+	"context"
+	"sync"
+)
+
+// CaptureAll captures every URL received from urls using concurrency tabs in
+// parallel and calls handle with each result. handle is called from several
+// goroutines at once. CaptureAll returns once urls is closed and every
+// capture has been handled.
+func (c *Capturer) CaptureAll(ctx context.Context, urls <-chan string, concurrency int, handle func(Result, error)) {
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for requestURL := range urls {
+				handle(c.Capture(ctx, requestURL))
+			}
+		}()
+	}
+	wg.Wait()
+}
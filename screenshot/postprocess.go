@@ -0,0 +1,180 @@
+package screenshot
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// trimTolerance is how far a channel of a pixel can be off the
+	// background for TrimWhitespace to still trim it, out of 0xffff
+	trimTolerance = 8 << 8
+	// watermarkScale is how many times the 13 pixels high font the
+	// watermark is drawn at, before Scale
+	watermarkScale  = 2
+	watermarkMargin = 8
+)
+
+var (
+	watermarkBackground = color.NRGBA{0, 0, 0, 0x60}
+	watermarkText       = color.NRGBA{0xff, 0xff, 0xff, 0xc0}
+)
+
+// postprocessing reports whether any of Crop, TrimWhitespace and Watermark
+// is set.
+func (o *Options) postprocessing() bool {
+	return !o.Crop.Empty() || o.TrimWhitespace || o.Watermark != ""
+}
+
+// postprocess crops, trims and watermarks the screenshots in res as set in
+// the options, in that order.
+func (c *Capturer) postprocess(res *Result) error {
+	if !c.opts.postprocessing() {
+		return nil
+	}
+	var err error
+	if res.Image, err = c.postprocessImage(res.Image); err != nil {
+		return err
+	}
+	for i := range res.Sizes {
+		if res.Sizes[i].Image, err = c.postprocessImage(res.Sizes[i].Image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Capturer) postprocessImage(data []byte) ([]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("processing screenshot: %w", err)
+	}
+	if !c.opts.Crop.Empty() {
+		r := c.opts.Crop.Add(img.Bounds().Min).Intersect(img.Bounds())
+		if r.Empty() {
+			return nil, fmt.Errorf("processing screenshot: crop %v is outside of the %dx%d screenshot", c.opts.Crop, img.Bounds().Dx(), img.Bounds().Dy())
+		}
+		img = subImage(img, r)
+	}
+	if c.opts.TrimWhitespace {
+		img = subImage(img, trimBounds(img))
+	}
+	if c.opts.Watermark != "" {
+		img = c.watermark(img)
+	}
+	data, err = c.encode(img)
+	if err != nil {
+		return nil, fmt.Errorf("processing screenshot: %w", err)
+	}
+	return data, nil
+}
+
+// subImage returns the part r of img.
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	out := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(out, out.Bounds(), img, r.Min, draw.Src)
+	return out
+}
+
+// trimBounds returns the bounds of img without the borders of the color of
+// its top left pixel, all of it if that is all there is.
+func trimBounds(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	br, bg, bb, ba := img.At(b.Min.X, b.Min.Y).RGBA()
+	background := func(x, y int) bool {
+		r, g, b, a := img.At(x, y).RGBA()
+		return near(r, br) && near(g, bg) && near(b, bb) && near(a, ba)
+	}
+	rowEmpty := func(y int) bool {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !background(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+	colEmpty := func(x, minY, maxY int) bool {
+		for y := minY; y < maxY; y++ {
+			if !background(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+
+	r := b
+	for r.Min.Y < r.Max.Y && rowEmpty(r.Min.Y) {
+		r.Min.Y++
+	}
+	if r.Min.Y == r.Max.Y {
+		return b
+	}
+	for rowEmpty(r.Max.Y - 1) {
+		r.Max.Y--
+	}
+	for colEmpty(r.Min.X, r.Min.Y, r.Max.Y) {
+		r.Min.X++
+	}
+	for colEmpty(r.Max.X-1, r.Min.Y, r.Max.Y) {
+		r.Max.X--
+	}
+	return r
+}
+
+func near(a, b uint32) bool {
+	if a > b {
+		return a-b <= trimTolerance
+	}
+	return b-a <= trimTolerance
+}
+
+// watermark returns img with Watermark in its bottom right corner, scaled up
+// along with the page like the banner of annotate.
+func (c *Capturer) watermark(img image.Image) image.Image {
+	scale := watermarkScale * int(math.Max(1, math.Round(c.opts.Scale)))
+	face := basicfont.Face7x13
+	text := image.NewRGBA(image.Rect(0, 0, len(c.opts.Watermark)*face.Advance+2*bannerPadding, face.Height+2*bannerPadding))
+	draw.Draw(text, text.Bounds(), image.NewUniform(watermarkBackground), image.Point{}, draw.Src)
+	d := font.Drawer{Dst: text, Src: image.NewUniform(watermarkText), Face: face}
+	d.Dot = fixed.P(bannerPadding, bannerPadding+face.Ascent)
+	d.DrawString(c.opts.Watermark)
+
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+	w, h := text.Bounds().Dx()*scale, text.Bounds().Dy()*scale
+	margin := watermarkMargin * scale
+	dst := image.Rect(b.Dx()-w-margin, b.Dy()-h-margin, b.Dx()-margin, b.Dy()-margin)
+	draw.NearestNeighbor.Scale(out, dst, text, text.Bounds(), draw.Over, nil)
+	return out
+}
+
+// encode encodes img in Format, which must not be WebP.
+func (c *Capturer) encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if c.opts.Format == FormatJPEG {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: int(c.opts.Quality)})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
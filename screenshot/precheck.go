@@ -0,0 +1,86 @@
+package screenshot
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// errUnreachable is returned for URLs that failed the Precheck.
+var errUnreachable = errors.New("unreachable")
+
+// newPrecheckClient returns the client of Precheck, going through the same
+// proxy, resolving hosts the same way and presenting the same client
+// certificate as Chrome. Responses are not followed or read, any response
+// shows the host is up.
+func newPrecheckClient(opts Options) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// like Chrome, which is started ignoring certificate errors
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	if opts.ClientCertificate != nil {
+		transport.TLSClientConfig.Certificates = []tls.Certificate{*opts.ClientCertificate}
+	}
+	if opts.Proxy != "" {
+		u, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", opts.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if len(opts.Resolve) > 0 {
+		var dialer net.Dialer
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if host, port, err := net.SplitHostPort(addr); err == nil {
+				if ip, ok := opts.Resolve[host]; ok {
+					addr = net.JoinHostPort(ip, port)
+				}
+			}
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
+
+// precheck makes sure the host of requestURL answers HTTP within Precheck, so
+// dead hosts fail without a tab waiting for them until Timeout. Servers that
+// drop HEAD requests are asked again with GET.
+func (c *Capturer) precheck(ctx context.Context, requestURL string) error {
+	if c.precheckClient == nil || !(strings.HasPrefix(requestURL, "http://") || strings.HasPrefix(requestURL, "https://")) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Precheck)
+	defer cancel()
+	var err error
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, method, requestURL, nil)
+		if err != nil {
+			return err
+		}
+		if c.userAgent != "" {
+			req.Header.Set("User-Agent", c.userAgent)
+		}
+		var resp *http.Response
+		resp, err = c.precheckClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		var opErr *net.OpError
+		if ctx.Err() != nil || errors.As(err, &opErr) && opErr.Op == "dial" {
+			// there is no server to ask again
+			break
+		}
+	}
+	return fmt.Errorf("%w: %w", errUnreachable, err)
+}
@@ -0,0 +1,70 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// defaultPorts are left out of probed URLs.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// hasScheme reports whether requestURL starts with a scheme, e.g. https://.
+func hasScheme(requestURL string) bool {
+	return strings.Contains(requestURL, "://")
+}
+
+// probeURLs returns the URLs to try for a bare hostname like example.com or
+// example.com:8080/path, using every one of Ports unless the host already has
+// one, each with every one of Schemes in order.
+func (c *Capturer) probeURLs(bare string) ([]string, error) {
+	u, err := url.Parse("//" + bare)
+	if err != nil {
+		return nil, err
+	}
+	ports := c.opts.Ports
+	if u.Port() != "" || len(ports) == 0 {
+		ports = []string{u.Port()}
+	}
+
+	var urls []string
+	for _, port := range ports {
+		for _, scheme := range c.opts.Schemes {
+			p := *u
+			p.Scheme = scheme
+			p.Host = u.Hostname()
+			if port != "" && port != defaultPorts[scheme] {
+				p.Host = net.JoinHostPort(u.Hostname(), port)
+			}
+			urls = append(urls, p.String())
+		}
+	}
+	return urls, nil
+}
+
+// probe captures the first of the probed URLs for bare that works and sets
+// res.URL to it.
+func (c *Capturer) probe(ctx context.Context, bare string, res *Result) error {
+	urls, err := c.probeURLs(bare)
+	if err != nil {
+		return err
+	}
+	// the probed URLs all have the same host
+	if len(urls) > 0 && !c.scope.allows(ctx, urls[0]) {
+		return fmt.Errorf("%s is %w", bare, errOutOfScope)
+	}
+	for _, requestURL := range urls {
+		res.URL = requestURL
+		err = c.captureWithRetries(ctx, requestURL, res)
+		if err == nil || ctx.Err() != nil || errors.Is(err, errDownload) {
+			return err
+		}
+	}
+	return fmt.Errorf("no scheme worked for %s, last error: %w", bare, err)
+}
@@ -0,0 +1,25 @@
+package screenshot
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// parseProxy splits a proxy URL as given to -proxy into the server Chrome
+// should use and the credentials to answer proxy auth challenges with, if any.
+func parseProxy(raw string) (server string, auth *url.Userinfo, err error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", nil, fmt.Errorf("invalid proxy %q, must be like socks5://host:port", raw)
+	}
+	auth = u.User
+	if auth != nil && (u.Scheme == "socks4" || u.Scheme == "socks5") {
+		// chrome only answers auth challenges for http(s) proxies
+		return "", nil, fmt.Errorf("chrome does not support authentication for %s proxies", u.Scheme)
+	}
+	u.User = nil
+	return u.String(), auth, nil
+}
@@ -0,0 +1,62 @@
+package screenshot
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// hostLimiter spaces out requests to the same host by a fixed interval.
+type hostLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func newHostLimiter(interval time.Duration) *hostLimiter {
+	return &hostLimiter{
+		interval: interval,
+		next:     map[string]time.Time{},
+	}
+}
+
+// wait blocks until a request to the host of requestURL may be made.
+func (l *hostLimiter) wait(ctx context.Context, requestURL string) error {
+	if l.interval <= 0 {
+		return nil
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		// let the navigation report the bad URL
+		return nil
+	}
+	host := u.Hostname()
+
+	l.mu.Lock()
+	now := time.Now()
+	t := l.next[host]
+	if t.Before(now) {
+		t = now
+	}
+	l.next[host] = t.Add(l.interval)
+	if len(l.next) > 1024 {
+		// forget hosts that are allowed again anyway
+		for h, n := range l.next {
+			if n.Before(now) {
+				delete(l.next, h)
+			}
+		}
+	}
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,74 @@
+package screenshot
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/chromedp/cdproto/fetch"
+)
+
+// Header is a single HTTP header, responses can have several of the same
+// name.
+type Header struct {
+	Name  string
+	Value string
+}
+
+// RequestMeta is a request made by a page and the response it got, as seen
+// at the fetch domain.
+type RequestMeta struct {
+	URL    string
+	Method string
+	Type   string // resource type, e.g. Document or Script
+	// Headers are the request headers, sorted by name.
+	Headers  []Header
+	PostData []byte
+	// Status and ResponseHeaders are those of the response, Error is why
+	// there is none.
+	Status          int64
+	ResponseHeaders []Header
+	Error           string
+}
+
+// requestRecorder collects the requests of a tab as their responses are
+// paused by handleRequests. It is safe for concurrent use.
+type requestRecorder struct {
+	mu       sync.Mutex
+	requests []RequestMeta
+}
+
+// record adds ev, a request paused at the response stage.
+func (r *requestRecorder) record(ev *fetch.EventRequestPaused) {
+	m := RequestMeta{
+		URL:    ev.Request.URL,
+		Method: ev.Request.Method,
+		Type:   ev.ResourceType.String(),
+		Status: ev.ResponseStatusCode,
+		Error:  ev.ResponseErrorReason.String(),
+	}
+	for name, value := range ev.Request.Headers {
+		m.Headers = append(m.Headers, Header{Name: name, Value: fmt.Sprint(value)})
+	}
+	sort.Slice(m.Headers, func(i, j int) bool { return m.Headers[i].Name < m.Headers[j].Name })
+	for _, e := range ev.Request.PostDataEntries {
+		data, _ := base64.StdEncoding.DecodeString(e.Bytes)
+		m.PostData = append(m.PostData, data...)
+	}
+	for _, h := range ev.ResponseHeaders {
+		m.ResponseHeaders = append(m.ResponseHeaders, Header{Name: h.Name, Value: h.Value})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, m)
+}
+
+// list returns the requests recorded so far, in the order of their
+// responses.
+func (r *requestRecorder) list() []RequestMeta {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RequestMeta(nil), r.requests...)
+}
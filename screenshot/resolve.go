@@ -0,0 +1,27 @@
+package screenshot
+
+import (
+	"net"
+	"sort"
+	"strings"
+)
+
+// resolverRules turns Resolve into Chrome's host-resolver-rules, like
+// "MAP example.com 10.0.0.1, MAP www.example.com 10.0.0.2".
+func resolverRules(resolve map[string]string) string {
+	hosts := make([]string, 0, len(resolve))
+	for host := range resolve {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	rules := make([]string, len(hosts))
+	for i, host := range hosts {
+		ip := resolve[host]
+		if strings.Contains(ip, ":") && net.ParseIP(ip) != nil {
+			ip = "[" + ip + "]"
+		}
+		rules[i] = "MAP " + host + " " + ip
+	}
+	return strings.Join(rules, ", ")
+}
@@ -0,0 +1,121 @@
+package screenshot
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// captureWithRetries calls capture, retrying up to Retries times with an
+// exponentially growing backoff in between. If SchemeFallback is set, https
+// URLs that fail with a network error are also tried over http on every
+// attempt, and res.URL is set to the http URL if that works.
+func (c *Capturer) captureWithRetries(ctx context.Context, requestURL string, res *Result) error {
+	backoff := c.opts.RetryBackoff
+	for {
+		res.Attempts++
+		err := c.capture(ctx, requestURL, res)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, errDownload) || errors.Is(err, errOverBudget) {
+			// would be downloaded again, or go over it again, and the host
+			// works
+			return err
+		}
+		if p := c.Policy(err); p != "" && p != PolicyRetry {
+			return err
+		}
+
+		if c.opts.SchemeFallback && isNetError(err) && strings.HasPrefix(requestURL, "https://") {
+			fallbackURL := "http://" + strings.TrimPrefix(requestURL, "https://")
+			ferr := c.capture(ctx, fallbackURL, res)
+			if ferr == nil {
+				res.URL = fallbackURL
+				return nil
+			}
+			// categorized by the https error, the one retried
+			err = fmt.Errorf("%w, and over http: %v", err, ferr)
+		}
+
+		if res.Attempts > c.opts.Retries {
+			return err
+		}
+		c.log.Debug("retrying", "url", requestURL, "attempt", res.Attempts, "backoff", backoff, "err", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// isNetError reports whether err is a Chrome network stack error such as
+// net::ERR_CONNECTION_REFUSED or net::ERR_NAME_NOT_RESOLVED.
+func isNetError(err error) bool {
+	return strings.Contains(err.Error(), "net::ERR_")
+}
+
+// Error categories returned by ErrorCategory.
+const (
+	CategoryTimeout    = "timeout"
+	CategoryCanceled   = "canceled"
+	CategoryDNS        = "dns"
+	CategoryTLS        = "tls"
+	CategoryRefused    = "connection-refused"
+	CategoryNetwork    = "network"
+	CategoryHTTPClient = "http-4xx"
+	CategoryHTTPServer = "http-5xx"
+	CategoryBrowser    = "browser"
+	CategoryScope      = "scope"
+	CategoryDownload   = "download"
+	CategoryBudget     = "budget"
+	CategoryOther      = "other"
+)
+
+// ErrorCategory roughly classifies an error returned by Capture, for logging,
+// statistics and Policies. Network errors of Chrome and of Precheck are told
+// apart into DNS, TLS and refused connections where possible.
+func ErrorCategory(err error) string {
+	var status *statusError
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	msg := err.Error()
+	switch {
+	case errors.As(err, &status) && status.status < 500:
+		return CategoryHTTPClient
+	case errors.As(err, &status):
+		return CategoryHTTPServer
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, errTabHung):
+		return CategoryTimeout
+	case errors.Is(err, context.Canceled):
+		return CategoryCanceled
+	case errors.As(err, &dnsErr), strings.Contains(msg, "net::ERR_NAME_NOT_RESOLVED"),
+		strings.Contains(msg, "net::ERR_NAME_RESOLUTION_FAILED"), strings.Contains(msg, "net::ERR_DNS_"):
+		return CategoryDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr), strings.Contains(msg, "net::ERR_SSL_"),
+		strings.Contains(msg, "net::ERR_CERT_"), strings.Contains(msg, "net::ERR_BAD_SSL_"):
+		return CategoryTLS
+	case errors.Is(err, syscall.ECONNREFUSED), strings.Contains(msg, "net::ERR_CONNECTION_REFUSED"):
+		return CategoryRefused
+	case isNetError(err), errors.Is(err, errUnreachable):
+		return CategoryNetwork
+	case errors.Is(err, errBrowserGone):
+		return CategoryBrowser
+	case errors.Is(err, errOutOfScope), errors.Is(err, errFileURL):
+		return CategoryScope
+	case errors.Is(err, errDownload):
+		return CategoryDownload
+	case errors.Is(err, errOverBudget):
+		return CategoryBudget
+	default:
+		return CategoryOther
+	}
+}
@@ -0,0 +1,66 @@
+package screenshot
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// processTreeRSS returns the resident memory in bytes of the process pid and
+// all of its descendants, which for Chrome are the renderer and GPU
+// processes. It needs /proc, so it only works on Linux.
+func processTreeRSS(pid int) (int64, error) {
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return 0, err
+	}
+	if len(stats) == 0 {
+		return 0, fmt.Errorf("no /proc to read memory usage from")
+	}
+	children := map[int][]int{}
+	for _, path := range stats {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// the process exited in the meantime
+			continue
+		}
+		// the command in parentheses may contain spaces, the parent is the
+		// second field after it
+		end := bytes.LastIndexByte(data, ')')
+		if end < 0 {
+			continue
+		}
+		fields := bytes.Fields(data[end+1:])
+		if len(fields) < 2 {
+			continue
+		}
+		child, err1 := strconv.Atoi(filepath.Base(filepath.Dir(path)))
+		parent, err2 := strconv.Atoi(string(fields[1]))
+		if err1 == nil && err2 == nil {
+			children[parent] = append(children[parent], child)
+		}
+	}
+
+	pageSize := int64(os.Getpagesize())
+	var total int64
+	queue := []int{pid}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = append(queue[1:], children[p]...)
+		// statm is in pages: size, resident, ...
+		data, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", p))
+		if err != nil {
+			continue
+		}
+		fields := bytes.Fields(data)
+		if len(fields) < 2 {
+			continue
+		}
+		if pages, err := strconv.ParseInt(string(fields[1]), 10, 64); err == nil {
+			total += pages * pageSize
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,127 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// errOutOfScope is returned for URLs outside the configured scope.
+var errOutOfScope = errors.New("out of scope")
+
+// scope decides which hosts may be loaded as pages. Patterns are CIDR
+// ranges, which hostnames are resolved for, or regular expressions matched
+// against the hostname.
+type scope struct {
+	include, exclude scopePatterns
+
+	mu       sync.Mutex
+	resolved map[string][]net.IP
+}
+
+type scopePatterns struct {
+	nets  []*net.IPNet
+	hosts []*regexp.Regexp
+}
+
+func newScope(include, exclude []string) (*scope, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+	s := &scope{resolved: map[string][]net.IP{}}
+	var err error
+	if s.include, err = parseScopePatterns(include); err != nil {
+		return nil, err
+	}
+	if s.exclude, err = parseScopePatterns(exclude); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func parseScopePatterns(patterns []string) (scopePatterns, error) {
+	var p scopePatterns
+	for _, raw := range patterns {
+		if _, ipnet, err := net.ParseCIDR(raw); err == nil {
+			p.nets = append(p.nets, ipnet)
+			continue
+		}
+		re, err := regexp.Compile(raw)
+		if err != nil {
+			return p, fmt.Errorf("invalid scope pattern %q, must be a CIDR range or regular expression: %w", raw, err)
+		}
+		p.hosts = append(p.hosts, re)
+	}
+	return p, nil
+}
+
+func (p scopePatterns) empty() bool {
+	return len(p.nets) == 0 && len(p.hosts) == 0
+}
+
+func (p scopePatterns) match(host string, ips []net.IP) bool {
+	for _, re := range p.hosts {
+		if re.MatchString(host) {
+			return true
+		}
+	}
+	for _, ipnet := range p.nets {
+		for _, ip := range ips {
+			if ipnet.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allows reports whether the host of rawURL is in scope: not excluded, and
+// included if there are include patterns. A nil scope allows everything.
+func (s *scope) allows(ctx context.Context, rawURL string) bool {
+	if s == nil {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https":
+	default:
+		// about:blank, data: etc. do not touch any host
+		return true
+	}
+	host := u.Hostname()
+	ips := s.lookup(ctx, host)
+	if s.exclude.match(host, ips) {
+		return false
+	}
+	return s.include.empty() || s.include.match(host, ips)
+}
+
+// lookup resolves host if there are CIDR patterns to match it against.
+func (s *scope) lookup(ctx context.Context, host string) []net.IP {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}
+	}
+	if len(s.include.nets) == 0 && len(s.exclude.nets) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	ips, ok := s.resolved[host]
+	s.mu.Unlock()
+	if ok {
+		return ips
+	}
+	// a host that does not resolve only matches regular expressions
+	ips, _ = net.DefaultResolver.LookupIP(ctx, "ip", host)
+	s.mu.Lock()
+	s.resolved[host] = ips
+	s.mu.Unlock()
+	return ips
+}
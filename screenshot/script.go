@@ -0,0 +1,93 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Step is a single action of a script run on every page before it is
+// captured, e.g. to log in or click through a wizard.
+type Step struct {
+	// Action is one of navigate, type, click or wait.
+	Action string `json:"action" yaml:"action"`
+	// URL is where navigate goes.
+	URL string `json:"url,omitempty" yaml:"url"`
+	// Selector is the CSS selector of the element type and click act on,
+	// or that wait waits to be visible.
+	Selector string `json:"selector,omitempty" yaml:"selector"`
+	// Text is what type types into the element.
+	Text string `json:"text,omitempty" yaml:"text"`
+	// Duration is how long wait waits if it has no selector.
+	Duration time.Duration `json:"duration,omitempty" yaml:"duration"`
+}
+
+// compileScript turns steps into the tasks to run.
+func compileScript(steps []Step) (chromedp.Tasks, error) {
+	var tasks chromedp.Tasks
+	for i, s := range steps {
+		var task chromedp.Action
+		switch {
+		case s.Action == "navigate" && s.URL != "":
+			task = chromedp.Navigate(s.URL)
+		case s.Action == "type" && s.Selector != "":
+			task = chromedp.SendKeys(s.Selector, s.Text, chromedp.ByQuery)
+		case s.Action == "click" && s.Selector != "":
+			task = chromedp.Click(s.Selector, chromedp.ByQuery)
+		case s.Action == "wait" && s.Selector != "":
+			task = chromedp.WaitVisible(s.Selector, chromedp.ByQuery)
+		case s.Action == "wait" && s.Duration > 0:
+			task = chromedp.Sleep(s.Duration)
+		case s.Action == "navigate":
+			return nil, fmt.Errorf("script step %d: navigate needs a url", i+1)
+		case s.Action == "type" || s.Action == "click":
+			return nil, fmt.Errorf("script step %d: %s needs a selector", i+1, s.Action)
+		case s.Action == "wait":
+			return nil, fmt.Errorf("script step %d: wait needs a selector or a duration", i+1)
+		default:
+			return nil, fmt.Errorf("script step %d: unknown action %q, must be navigate, type, click or wait", i+1, s.Action)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// evaluate runs the Eval snippets, which together have Timeout to finish.
+func (c *Capturer) evaluate() chromedp.Action {
+	var tasks chromedp.Tasks
+	for i, js := range c.opts.Eval {
+		i, js := i, js
+		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+			err := chromedp.Evaluate(js, nil, awaitPromise).Do(ctx)
+			if err != nil {
+				return fmt.Errorf("evaluating script %d: %w", i+1, err)
+			}
+			return nil
+		}))
+	}
+	if len(tasks) == 0 {
+		return tasks
+	}
+	return withTimeout(c.opts.Timeout, tasks)
+}
+
+// runScript runs the compiled Script, if any.
+func (c *Capturer) runScript() chromedp.Action {
+	if len(c.script) == 0 {
+		return chromedp.Tasks{}
+	}
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := withTimeout(c.opts.Timeout, c.script).Do(ctx); err != nil {
+			return fmt.Errorf("running script: %w", err)
+		}
+		return nil
+	})
+}
+
+// awaitPromise makes Evaluate wait for the promise the script returns.
+func awaitPromise(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+	return p.WithAwaitPromise(true)
+}
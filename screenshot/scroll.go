@@ -0,0 +1,42 @@
+package screenshot
+
+import (
+	"context"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// maxScrollSteps bounds scrolling infinite scroll pages, which never reach
+// the bottom.
+const maxScrollSteps = 50
+
+// scrollStepJS scrolls down by a viewport and reports whether the bottom of
+// the page was reached.
+const scrollStepJS = `(() => {
+	window.scrollBy(0, window.innerHeight);
+	return window.scrollY + window.innerHeight >= document.documentElement.scrollHeight - 1;
+})()`
+
+// scroll scrolls to the bottom of the page a viewport at a time, waiting
+// ScrollDelay after every step for lazy loaded content to show up, then back
+// to the top.
+func (c *Capturer) scroll() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		for i := 0; i < maxScrollSteps; i++ {
+			var bottom bool
+			if err := chromedp.Evaluate(scrollStepJS, &bottom).Do(ctx); err != nil {
+				return err
+			}
+			select {
+			case <-time.After(c.opts.ScrollDelay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if bottom {
+				break
+			}
+		}
+		return chromedp.Evaluate(`window.scrollTo(0, 0)`, nil).Do(ctx)
+	})
+}
@@ -0,0 +1,155 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/security"
+	"github.com/chromedp/chromedp"
+)
+
+// Security summarizes the security relevant headers of the main document of
+// a page, the flags of its cookies and the insecure resources it requested.
+type Security struct {
+	// Score is 100 for a page doing everything checked for, less for
+	// every Issue.
+	Score  int      `json:"score"`
+	Issues []string `json:"issues,omitempty"`
+
+	CSP                string        `json:"csp,omitempty"`
+	HSTS               string        `json:"hsts,omitempty"`
+	FrameOptions       string        `json:"x_frame_options,omitempty"`
+	ContentTypeOptions string        `json:"x_content_type_options,omitempty"`
+	ReferrerPolicy     string        `json:"referrer_policy,omitempty"`
+	Cookies            []CookieFlags `json:"cookies,omitempty"`
+	// MixedContent are the http resources requested by an https page,
+	// which Chrome blocks unless they are images, audio or video.
+	MixedContent []MixedContent `json:"mixed_content,omitempty"`
+}
+
+// CookieFlags are the security attributes of a cookie the page set.
+type CookieFlags struct {
+	Name     string `json:"name"`
+	Domain   string `json:"domain"`
+	Secure   bool   `json:"secure"`
+	HTTPOnly bool   `json:"http_only"`
+	SameSite string `json:"same_site,omitempty"`
+}
+
+// MixedContent is an insecure resource requested by a secure page.
+type MixedContent struct {
+	URL string `json:"url"`
+	// Blockable is set for resources Chrome blocks, like scripts, and
+	// unset for those it loads anyway, like images.
+	Blockable bool `json:"blockable"`
+}
+
+// mixedContentRecorder collects the insecure requests of a tab.
+type mixedContentRecorder struct {
+	mu       sync.Mutex
+	requests []MixedContent
+}
+
+// recordMixedContent starts recording the mixed content requested by the tab
+// behind ctx.
+func recordMixedContent(ctx context.Context) *mixedContentRecorder {
+	r := &mixedContentRecorder{}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		e, ok := ev.(*network.EventRequestWillBeSent)
+		if !ok || e.Request == nil {
+			return
+		}
+		switch e.Request.MixedContentType {
+		case security.MixedContentTypeBlockable, security.MixedContentTypeOptionallyBlockable:
+			r.mu.Lock()
+			r.requests = append(r.requests, MixedContent{
+				URL:       e.Request.URL,
+				Blockable: e.Request.MixedContentType == security.MixedContentTypeBlockable,
+			})
+			r.mu.Unlock()
+		}
+	})
+	return r
+}
+
+func (r *mixedContentRecorder) list() []MixedContent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]MixedContent(nil), r.requests...)
+}
+
+// readCookies stores the cookies the page at pageURL can see in res if
+// Security is set.
+func (c *Capturer) readCookies(pageURL *string, res *[]*network.Cookie) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.Security {
+			return nil
+		}
+		var err error
+		*res, err = network.GetCookies().WithUrls([]string{*pageURL}).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("reading cookies: %w", err)
+		}
+		return nil
+	})
+}
+
+// securityPosture scores the page at pageURL by the headers of its main
+// document, its cookies and the mixed content it requested.
+func securityPosture(pageURL string, headers network.Headers, cookies []*network.Cookie, mixed []MixedContent) *Security {
+	s := &Security{
+		Score:              100,
+		CSP:                headerValue(headers, "Content-Security-Policy"),
+		HSTS:               headerValue(headers, "Strict-Transport-Security"),
+		FrameOptions:       headerValue(headers, "X-Frame-Options"),
+		ContentTypeOptions: headerValue(headers, "X-Content-Type-Options"),
+		ReferrerPolicy:     headerValue(headers, "Referrer-Policy"),
+		MixedContent:       mixed,
+	}
+	issue := func(penalty int, text string) {
+		s.Score -= penalty
+		s.Issues = append(s.Issues, text)
+	}
+	https := strings.HasPrefix(pageURL, "https://")
+	csp := strings.ToLower(s.CSP)
+
+	if !https {
+		issue(30, "not served over https")
+	} else if s.HSTS == "" {
+		issue(15, "no Strict-Transport-Security")
+	}
+	switch {
+	case s.CSP == "":
+		issue(20, "no Content-Security-Policy")
+	case (strings.Contains(csp, "'unsafe-inline'") || strings.Contains(csp, "'unsafe-eval'")) && !strings.Contains(csp, "'nonce-") && !strings.Contains(csp, "'strict-dynamic'"):
+		issue(10, "Content-Security-Policy allows unsafe-inline or unsafe-eval")
+	}
+	if s.FrameOptions == "" && !strings.Contains(csp, "frame-ancestors") {
+		issue(10, "no X-Frame-Options or frame-ancestors")
+	}
+	if !strings.EqualFold(strings.TrimSpace(s.ContentTypeOptions), "nosniff") {
+		issue(5, "no X-Content-Type-Options: nosniff")
+	}
+
+	insecure := 0
+	for _, c := range cookies {
+		f := CookieFlags{Name: c.Name, Domain: c.Domain, Secure: c.Secure, HTTPOnly: c.HTTPOnly, SameSite: string(c.SameSite)}
+		s.Cookies = append(s.Cookies, f)
+		if https && !f.Secure || f.SameSite == string(network.CookieSameSiteNone) && !f.Secure {
+			insecure++
+		}
+	}
+	sort.Slice(s.Cookies, func(i, j int) bool { return s.Cookies[i].Name < s.Cookies[j].Name })
+	if insecure > 0 {
+		issue(min(5*insecure, 15), fmt.Sprintf("%d cookies without Secure", insecure))
+	}
+	if len(mixed) > 0 {
+		issue(15, fmt.Sprintf("%d insecure resources requested", len(mixed)))
+	}
+	s.Score = max(s.Score, 0)
+	return s
+}
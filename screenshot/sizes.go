@@ -0,0 +1,49 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// sizeSettle is how long a page gets to respond to the viewport being resized
+// before it is captured again, e.g. for resize handlers and responsive images.
+const sizeSettle = 250 * time.Millisecond
+
+// Size is a viewport size.
+type Size struct {
+	Width  int64
+	Height int64
+}
+
+func (s Size) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// SizedImage is a screenshot in one of the Sizes.
+type SizedImage struct {
+	Size
+	Image []byte
+}
+
+// captureSizes takes a screenshot in each of Sizes into res, each within
+// CaptureTimeout.
+func (c *Capturer) captureSizes(res *[]SizedImage) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		for _, size := range c.opts.Sizes {
+			img := SizedImage{Size: size}
+			err := withTimeout(c.opts.CaptureTimeout, chromedp.Tasks{
+				c.emulateViewport(size.Width, size.Height),
+				chromedp.Sleep(sizeSettle),
+				c.fullScreenshot(size.Width, size.Height, &img.Image),
+			}).Do(ctx)
+			if err != nil {
+				return fmt.Errorf("capturing at %s: %w", size, err)
+			}
+			*res = append(*res, img)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,184 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Technology is a framework, server, CMS etc. a page was found to use.
+type Technology struct {
+	Name     string `json:"name"`
+	Version  string `json:"version,omitempty"`
+	Category string `json:"category"`
+}
+
+// techRule detects a technology. Any matching part is enough, the first
+// group of a matching regular expression, if it has one, is the version.
+type techRule struct {
+	name     string
+	category string
+	// headers maps response header names of the main document to the
+	// pattern their value must match
+	headers map[string]*regexp.Regexp
+	// cookies are names of cookies set by it
+	cookies []string
+	// script matches the URL of a script on the page
+	script *regexp.Regexp
+	// generator matches the content of <meta name="generator">
+	generator *regexp.Regexp
+	// global is a JavaScript expression that is truthy on pages using it.
+	// If it evaluates to a string, that is the version.
+	global string
+}
+
+var techRe = regexp.MustCompile
+
+// techRules are the technologies detected, grouped by category.
+var techRules = []techRule{
+	{name: "Atlassian Confluence", category: "app", headers: map[string]*regexp.Regexp{"X-Confluence-Request-Time": techRe(``)}, global: `window.AJS && document.querySelector('meta[name=ajs-version-number]')?.content`},
+	{name: "Atlassian Jira", category: "app", headers: map[string]*regexp.Regexp{"X-AREQUESTID": techRe(``)}, global: `window.JIRA && (document.querySelector('meta[name=ajs-version-number]')?.content || true)`},
+	{name: "GitLab", category: "app", cookies: []string{"_gitlab_session"}, global: `window.gon && window.gon.gitlab_url && true`},
+	{name: "Grafana", category: "app", global: `window.grafanaBootData && (window.grafanaBootData.settings?.buildInfo?.version || true)`},
+	{name: "Jenkins", category: "app", headers: map[string]*regexp.Regexp{"X-Jenkins": techRe(`([\d.]+)`)}},
+	{name: "Kibana", category: "app", headers: map[string]*regexp.Regexp{"kbn-version": techRe(`([\d.]+)`), "kbn-name": techRe(``)}},
+	{name: "phpMyAdmin", category: "app", cookies: []string{"phpMyAdmin"}, global: `window.PMA_commonParams && true`},
+	{name: "Drupal", category: "cms", headers: map[string]*regexp.Regexp{"X-Generator": techRe(`Drupal (\d+)`), "X-Drupal-Cache": techRe(``)}, generator: techRe(`Drupal (\d+)`), global: `window.Drupal && true`},
+	{name: "Joomla", category: "cms", generator: techRe(`Joomla!? ?([\d.]+)?`)},
+	{name: "Magento", category: "cms", cookies: []string{"X-Magento-Vary"}, script: techRe(`/static/version\d+/frontend/`)},
+	{name: "Shopify", category: "cms", headers: map[string]*regexp.Regexp{"X-ShopId": techRe(``)}, global: `window.Shopify && true`},
+	{name: "WordPress", category: "cms", generator: techRe(`WordPress ?([\d.]+)?`), script: techRe(`/wp-(?:content|includes)/`)},
+	{name: "Cloudflare", category: "cdn", headers: map[string]*regexp.Regexp{"Server": techRe(`^cloudflare$`), "CF-Ray": techRe(``)}, cookies: []string{"__cf_bm", "__cfruid"}},
+	{name: "Varnish", category: "cache", headers: map[string]*regexp.Regexp{"X-Varnish": techRe(``), "Via": techRe(`varnish`)}},
+	{name: "Angular", category: "framework", global: `document.querySelector('[ng-version]')?.getAttribute('ng-version')`},
+	{name: "AngularJS", category: "framework", global: `window.angular && window.angular.version?.full`},
+	{name: "ASP.NET", category: "framework", headers: map[string]*regexp.Regexp{"X-AspNet-Version": techRe(`([\d.]+)`), "X-Powered-By": techRe(`ASP\.NET`)}, cookies: []string{"ASP.NET_SessionId", "ASPSESSIONID"}},
+	{name: "Bootstrap", category: "framework", script: techRe(`bootstrap(?:\.bundle)?(?:\.min)?\.js`), global: `window.bootstrap?.Tooltip?.VERSION || (window.jQuery?.fn?.tooltip?.Constructor?.VERSION)`},
+	{name: "Django", category: "framework", cookies: []string{"csrftoken", "django_language"}},
+	{name: "Express", category: "framework", headers: map[string]*regexp.Regexp{"X-Powered-By": techRe(`^Express$`)}},
+	{name: "jQuery", category: "framework", script: techRe(`jquery[.-]?([\d.]+\d)?(?:\.min)?\.js`), global: `window.jQuery?.fn?.jquery`},
+	{name: "Laravel", category: "framework", cookies: []string{"laravel_session", "XSRF-TOKEN"}},
+	{name: "Next.js", category: "framework", headers: map[string]*regexp.Regexp{"X-Powered-By": techRe(`^Next\.js ?([\d.]+)?`)}, global: `window.__NEXT_DATA__ && (window.next?.version || true)`},
+	{name: "Nuxt", category: "framework", global: `(window.__NUXT__ || window.$nuxt) && true`},
+	{name: "React", category: "framework", global: `window.React?.version || (document.querySelector('[data-reactroot], [data-reactid]') && true)`},
+	{name: "Ruby on Rails", category: "framework", cookies: []string{"_rails_session"}, headers: map[string]*regexp.Regexp{"X-Runtime": techRe(`^[\d.]+$`)}, global: `document.querySelector('meta[name=csrf-param][content=authenticity_token]') && true`},
+	{name: "Vue.js", category: "framework", global: `window.Vue?.version || (document.querySelector('[data-v-app], [data-server-rendered]') && true)`},
+	{name: "Java", category: "language", cookies: []string{"JSESSIONID"}},
+	{name: "PHP", category: "language", headers: map[string]*regexp.Regexp{"X-Powered-By": techRe(`PHP/?([\d.]+)?`)}, cookies: []string{"PHPSESSID"}},
+	{name: "Apache HTTP Server", category: "server", headers: map[string]*regexp.Regexp{"Server": techRe(`Apache(?:/([\d.]+))?(?:$| )`)}},
+	{name: "Apache Tomcat", category: "server", headers: map[string]*regexp.Regexp{"Server": techRe(`Apache-Coyote|Tomcat`)}},
+	{name: "Caddy", category: "server", headers: map[string]*regexp.Regexp{"Server": techRe(`^Caddy`)}},
+	{name: "Microsoft IIS", category: "server", headers: map[string]*regexp.Regexp{"Server": techRe(`Microsoft-IIS(?:/([\d.]+))?`)}},
+	{name: "nginx", category: "server", headers: map[string]*regexp.Regexp{"Server": techRe(`nginx(?:/([\d.]+))?`)}},
+	{name: "Google Analytics", category: "analytics", script: techRe(`google-analytics\.com/|googletagmanager\.com/gtag`), global: `(window.ga || window.gtag) && true`},
+	{name: "Google Tag Manager", category: "analytics", script: techRe(`googletagmanager\.com/gtm\.js`)},
+}
+
+// techGlobals is the JavaScript expression evaluating every rule's global,
+// in the order of techRules.
+var techGlobals = func() string {
+	js := "["
+	for _, r := range techRules {
+		expr := "null"
+		if r.global != "" {
+			expr = r.global
+		}
+		js += fmt.Sprintf("(() => { try { return %s; } catch (e) { return null; } })(),\n", expr)
+	}
+	return js + "]"
+}()
+
+// techPageJS collects what the rules look at from the page.
+var techPageJS = fmt.Sprintf(`(() => {
+	const globals = %s;
+	return {
+		scripts: Array.from(document.scripts, s => s.src).filter(s => s),
+		generator: document.querySelector('meta[name=generator]')?.content || '',
+		globals: globals.map(v => v ? (typeof v === 'string' ? v : '') : null),
+	};
+})()`, techGlobals)
+
+type techPage struct {
+	Scripts   []string  `json:"scripts"`
+	Generator string    `json:"generator"`
+	Globals   []*string `json:"globals"`
+}
+
+// techEvidence is what technologies are detected from.
+type techEvidence struct {
+	page    techPage
+	cookies []*network.Cookie
+}
+
+// collectTech gathers the evidence in ev if DetectTech is set.
+func (c *Capturer) collectTech(ev *techEvidence) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if !c.opts.DetectTech {
+			return nil
+		}
+		if err := chromedp.Evaluate(techPageJS, &ev.page).Do(ctx); err != nil {
+			return fmt.Errorf("detecting technologies: %w", err)
+		}
+		var err error
+		ev.cookies, err = network.GetCookies().Do(ctx)
+		return err
+	})
+}
+
+// detectTech returns the technologies the rules find in the main document's
+// response headers and the evidence gathered from the page.
+func detectTech(headers network.Headers, ev techEvidence) []Technology {
+	cookies := map[string]bool{}
+	for _, c := range ev.cookies {
+		cookies[c.Name] = true
+	}
+
+	var found []Technology
+	for i, r := range techRules {
+		version, ok := "", false
+		match := func(pattern *regexp.Regexp, s string) {
+			m := pattern.FindStringSubmatch(s)
+			if m == nil {
+				return
+			}
+			ok = true
+			if len(m) > 1 && m[1] != "" && version == "" {
+				version = m[1]
+			}
+		}
+
+		for name, pattern := range r.headers {
+			if v := headerValue(headers, name); v != "" {
+				match(pattern, v)
+			}
+		}
+		for _, name := range r.cookies {
+			ok = ok || cookies[name]
+		}
+		if r.script != nil {
+			for _, src := range ev.page.Scripts {
+				match(r.script, src)
+			}
+		}
+		if r.generator != nil && ev.page.Generator != "" {
+			match(r.generator, ev.page.Generator)
+		}
+		if i < len(ev.page.Globals) && ev.page.Globals[i] != nil {
+			ok = true
+			if v := *ev.page.Globals[i]; v != "" {
+				version = v
+			}
+		}
+
+		if ok {
+			found = append(found, Technology{Name: r.name, Version: version, Category: r.category})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return strings.ToLower(found[i].Name) < strings.ToLower(found[j].Name) })
+	return found
+}
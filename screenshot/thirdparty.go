@@ -0,0 +1,183 @@
+package screenshot
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ThirdParty is a site other than its own a page made requests to.
+type ThirdParty struct {
+	// Domain is the registrable domain of the site, e.g.
+	// googletagmanager.com, or the IP address requests went to.
+	Domain string `json:"domain"`
+	// Category is one of analytics, ads, cdn, consent, fonts, monitoring,
+	// social, support and tag-manager for well known sites.
+	Category string   `json:"category,omitempty"`
+	Hosts    []string `json:"hosts"`
+	Requests int      `json:"requests"`
+	// Types are the resource types requested, e.g. Script or Image.
+	Types []string `json:"types"`
+}
+
+// thirdPartyCategories are the categories of well known third party sites,
+// by registrable domain.
+var thirdPartyCategories = map[string]string{
+	"google-analytics.com":         "analytics",
+	"hotjar.com":                   "analytics",
+	"mixpanel.com":                 "analytics",
+	"segment.com":                  "analytics",
+	"segment.io":                   "analytics",
+	"clarity.ms":                   "analytics",
+	"matomo.cloud":                 "analytics",
+	"plausible.io":                 "analytics",
+	"doubleclick.net":              "ads",
+	"googlesyndication.com":        "ads",
+	"googleadservices.com":         "ads",
+	"adnxs.com":                    "ads",
+	"criteo.com":                   "ads",
+	"taboola.com":                  "ads",
+	"cloudfront.net":               "cdn",
+	"akamaihd.net":                 "cdn",
+	"fastly.net":                   "cdn",
+	"jsdelivr.net":                 "cdn",
+	"unpkg.com":                    "cdn",
+	"cdnjs.com":                    "cdn",
+	"jquery.com":                   "cdn",
+	"bootstrapcdn.com":             "cdn",
+	"gstatic.com":                  "cdn",
+	"cookiebot.com":                "consent",
+	"cookielaw.org":                "consent",
+	"onetrust.com":                 "consent",
+	"usercentrics.eu":              "consent",
+	"typekit.net":                  "fonts",
+	"fonts.net":                    "fonts",
+	"sentry.io":                    "monitoring",
+	"nr-data.net":                  "monitoring",
+	"newrelic.com":                 "monitoring",
+	"datadoghq.com":                "monitoring",
+	"browser-intake-datadoghq.com": "monitoring",
+	"facebook.net":                 "social",
+	"facebook.com":                 "social",
+	"twitter.com":                  "social",
+	"linkedin.com":                 "social",
+	"licdn.com":                    "social",
+	"intercom.io":                  "support",
+	"zendesk.com":                  "support",
+	"zdassets.com":                 "support",
+	"googletagmanager.com":         "tag-manager",
+	"tealiumiq.com":                "tag-manager",
+}
+
+// thirdPartyHosts are category overrides for hosts of sites with several
+// uses.
+var thirdPartyHosts = map[string]string{
+	"fonts.googleapis.com":          "fonts",
+	"ajax.googleapis.com":           "cdn",
+	"cdnjs.cloudflare.com":          "cdn",
+	"static.cloudflareinsights.com": "analytics",
+}
+
+// siteOf returns the registrable domain of host, the host itself for IP
+// addresses and names without a public suffix.
+func siteOf(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	site, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return site
+}
+
+// thirdPartyRecorder counts the requests of a tab by host and resource type.
+// It is safe for concurrent use.
+type thirdPartyRecorder struct {
+	mu    sync.Mutex
+	hosts map[string]map[string]int
+}
+
+// recordThirdParties starts recording the requests of the tab behind ctx.
+func recordThirdParties(ctx context.Context) *thirdPartyRecorder {
+	r := &thirdPartyRecorder{hosts: map[string]map[string]int{}}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		e, ok := ev.(*network.EventRequestWillBeSent)
+		if !ok || e.Request == nil {
+			return
+		}
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || u.Hostname() == "" || !(u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "ws" || u.Scheme == "wss") {
+			return
+		}
+		host := strings.ToLower(u.Hostname())
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.hosts[host] == nil {
+			r.hosts[host] = map[string]int{}
+		}
+		r.hosts[host][e.Type.String()]++
+	})
+	return r
+}
+
+// list returns the sites requested other than those of the pages in
+// firstParty, those with the most requests first.
+func (r *thirdPartyRecorder) list(firstParty ...string) []ThirdParty {
+	own := map[string]bool{}
+	for _, page := range firstParty {
+		if u, err := url.Parse(page); err == nil && u.Hostname() != "" {
+			own[siteOf(u.Hostname())] = true
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bySite := map[string]*ThirdParty{}
+	types := map[string]map[string]bool{}
+	for host, counts := range r.hosts {
+		site := siteOf(host)
+		if own[site] {
+			continue
+		}
+		tp := bySite[site]
+		if tp == nil {
+			tp = &ThirdParty{Domain: site, Category: thirdPartyCategories[site]}
+			bySite[site] = tp
+			types[site] = map[string]bool{}
+		}
+		tp.Hosts = append(tp.Hosts, host)
+		if category, ok := thirdPartyHosts[host]; ok {
+			tp.Category = category
+		}
+		for typ, n := range counts {
+			tp.Requests += n
+			types[site][typ] = true
+		}
+	}
+
+	list := make([]ThirdParty, 0, len(bySite))
+	for site, tp := range bySite {
+		sort.Strings(tp.Hosts)
+		for typ := range types[site] {
+			tp.Types = append(tp.Types, typ)
+		}
+		sort.Strings(tp.Types)
+		list = append(list, *tp)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Requests != list[j].Requests {
+			return list[i].Requests > list[j].Requests
+		}
+		return list[i].Domain < list[j].Domain
+	})
+	return list
+}
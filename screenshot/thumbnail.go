@@ -0,0 +1,44 @@
+package screenshot
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+
+	"golang.org/x/image/draw"
+)
+
+// maxThumbnailAspect is how many times taller than wide a thumbnail can be.
+// Full page screenshots are cut off below that, the top of the page is what
+// identifies it.
+const maxThumbnailAspect = 2
+
+// Thumbnail scales an encoded screenshot down to width pixels wide and
+// returns it as a JPEG. Screenshots narrower than width are only re-encoded.
+func Thumbnail(data []byte, width int) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	src := img.Bounds()
+	if h := src.Dx() * maxThumbnailAspect; src.Dy() > h {
+		src.Max.Y = src.Min.Y + h
+	}
+	if width > src.Dx() {
+		width = src.Dx()
+	}
+	height := src.Dy() * width / src.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
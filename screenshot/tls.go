@@ -0,0 +1,40 @@
+package screenshot
+
+import (
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// TLSDetails describes the connection and certificate a page was served
+// over, for HTTPS pages.
+type TLSDetails struct {
+	Protocol  string    `json:"protocol"`
+	Cipher    string    `json:"cipher"`
+	Subject   string    `json:"subject"`
+	SANs      []string  `json:"sans,omitempty"`
+	Issuer    string    `json:"issuer"`
+	ValidFrom time.Time `json:"valid_from"`
+	ValidTo   time.Time `json:"valid_to"`
+}
+
+// tlsDetails converts the security details Chrome reports for a response.
+func tlsDetails(d *network.SecurityDetails) *TLSDetails {
+	if d == nil {
+		return nil
+	}
+	t := &TLSDetails{
+		Protocol: d.Protocol,
+		Cipher:   d.Cipher,
+		Subject:  d.SubjectName,
+		SANs:     d.SanList,
+		Issuer:   d.Issuer,
+	}
+	if d.ValidFrom != nil {
+		t.ValidFrom = d.ValidFrom.Time().UTC()
+	}
+	if d.ValidTo != nil {
+		t.ValidTo = d.ValidTo.Time().UTC()
+	}
+	return t
+}
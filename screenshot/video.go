@@ -0,0 +1,127 @@
+package screenshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// videoMaxWidth and videoMaxHeight bound the size of recorded frames to keep
+// the GIFs reasonably small.
+const (
+	videoMaxWidth  = 960
+	videoMaxHeight = 960
+)
+
+type videoFrame struct {
+	jpeg []byte
+	at   time.Time
+}
+
+// recordVideo records a screencast of the page for Video and stores it in
+// res as an animated GIF. Chrome only sends a frame when the page changes, so
+// a static page gives a single frame.
+func (c *Capturer) recordVideo(res *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if c.opts.Video <= 0 {
+			return nil
+		}
+
+		var (
+			mu     sync.Mutex
+			frames []videoFrame
+		)
+		lctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		chromedp.ListenTarget(lctx, func(ev interface{}) {
+			frame, ok := ev.(*page.EventScreencastFrame)
+			if !ok {
+				return
+			}
+			go func() {
+				t := chromedp.FromContext(ctx).Target
+				_ = page.ScreencastFrameAck(frame.SessionID).Do(cdp.WithExecutor(ctx, t))
+			}()
+
+			data, err := base64.StdEncoding.DecodeString(frame.Data)
+			if err != nil {
+				return
+			}
+			at := time.Now()
+			if frame.Metadata != nil && frame.Metadata.Timestamp != nil {
+				at = frame.Metadata.Timestamp.Time()
+			}
+			mu.Lock()
+			frames = append(frames, videoFrame{jpeg: data, at: at})
+			mu.Unlock()
+		})
+
+		err := page.StartScreencast().
+			WithFormat(page.ScreencastFormatJpeg).
+			WithQuality(80).
+			WithMaxWidth(videoMaxWidth).
+			WithMaxHeight(videoMaxHeight).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+		started := time.Now()
+		select {
+		case <-time.After(c.opts.Video):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := page.StopScreencast().Do(ctx); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(frames) == 0 {
+			return nil
+		}
+		*res, err = encodeGIF(frames, started.Add(c.opts.Video))
+		return err
+	})
+}
+
+// encodeGIF assembles frames into an animated GIF, showing each frame until
+// the next one and the last one until end.
+func encodeGIF(frames []videoFrame, end time.Time) ([]byte, error) {
+	anim := &gif.GIF{}
+	for i, f := range frames {
+		next := end
+		if i+1 < len(frames) {
+			next = frames[i+1].at
+		}
+		// delays are in 100ths of a second
+		delay := max(int(next.Sub(f.at)/(10*time.Millisecond)), 2)
+
+		img, err := jpeg.Decode(bytes.NewReader(f.jpeg))
+		if err != nil {
+			return nil, err
+		}
+		b := img.Bounds()
+		paletted := image.NewPaletted(b, palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, b, img, b.Min)
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
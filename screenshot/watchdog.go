@@ -0,0 +1,58 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// watchdogGrace is how long a tab is given past its deadline to wind down
+// before the watchdog closes it.
+const watchdogGrace = 10 * time.Second
+
+// errTabHung is returned when a tab did not return in time even after its
+// context was canceled, which happens when Chrome stops answering it.
+var errTabHung = errors.New("tab hung")
+
+// watchTab runs captureTab with a watchdog. chromedp only notices a canceled
+// context between commands, so a tab whose CDP connection hangs would
+// otherwise block its worker forever. Once the tab is past its deadline the
+// watchdog closes it through the browser and gives up on it.
+func (c *Capturer) watchTab(ctx, pctx context.Context, requestURL string, res *Result) error {
+	// the abandoned tab may still write to its result, so it gets a copy
+	tabRes := *res
+	tab := make(chan target.ID, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.captureTab(ctx, pctx, requestURL, &tabRes, tab)
+	}()
+
+	timeout := c.tabTimeout() + watchdogGrace
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		*res = tabRes
+		return err
+	case <-timer.C:
+	}
+
+	select {
+	case id := <-tab:
+		c.opts.Logger.Warn("closing hung tab", "url", requestURL, "timeout", timeout)
+		cctx, cancel := context.WithTimeout(pctx, watchdogGrace)
+		defer cancel()
+		b := chromedp.FromContext(pctx).Browser
+		if err := target.CloseTarget(id).Do(cdp.WithExecutor(cctx, b)); err != nil {
+			c.opts.Logger.Warn("closing hung tab failed", "url", requestURL, "err", err)
+		}
+	default:
+		// the tab never opened, nothing to close
+	}
+	return fmt.Errorf("%w after %s", errTabHung, timeout)
+}
@@ -0,0 +1,132 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// WebSocketMeta is a WebSocket connection made by a page, its handshake and
+// its first frames.
+type WebSocketMeta struct {
+	URL string
+	// Headers are those of the handshake request, Status and
+	// ResponseHeaders of its response. Both are sorted by name.
+	Headers         []Header
+	Status          int64
+	ResponseHeaders []Header
+	Frames          []WebSocketFrame
+	// Dropped is how many frames were left out after the first
+	// WebSocketFrames.
+	Dropped int
+	Error   string
+}
+
+// WebSocketFrame is a message sent or received over a WebSocket.
+type WebSocketFrame struct {
+	Time time.Time
+	Sent bool
+	// Opcode is 1 for text and 2 for binary messages.
+	Opcode int
+	Data   []byte
+}
+
+// webSocketRecorder collects the WebSockets of a tab and the first max frames
+// of each. It is safe for concurrent use.
+type webSocketRecorder struct {
+	max int
+
+	mu      sync.Mutex
+	sockets map[network.RequestID]*WebSocketMeta
+	order   []network.RequestID
+}
+
+// recordWebSockets starts recording the WebSockets of the tab behind ctx.
+func recordWebSockets(ctx context.Context, maxFrames int) *webSocketRecorder {
+	r := &webSocketRecorder{max: maxFrames, sockets: map[network.RequestID]*WebSocketMeta{}}
+	chromedp.ListenTarget(ctx, r.handle)
+	return r
+}
+
+func (r *webSocketRecorder) handle(ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch ev := ev.(type) {
+	case *network.EventWebSocketCreated:
+		if r.sockets[ev.RequestID] == nil {
+			r.order = append(r.order, ev.RequestID)
+		}
+		r.sockets[ev.RequestID] = &WebSocketMeta{URL: ev.URL}
+	case *network.EventWebSocketWillSendHandshakeRequest:
+		if ws := r.sockets[ev.RequestID]; ws != nil && ev.Request != nil {
+			ws.Headers = sortedHeaders(ev.Request.Headers)
+		}
+	case *network.EventWebSocketHandshakeResponseReceived:
+		if ws := r.sockets[ev.RequestID]; ws != nil && ev.Response != nil {
+			ws.Status = ev.Response.Status
+			ws.ResponseHeaders = sortedHeaders(ev.Response.Headers)
+			if len(ev.Response.RequestHeaders) > 0 {
+				// what was actually sent, with cookies
+				ws.Headers = sortedHeaders(ev.Response.RequestHeaders)
+			}
+		}
+	case *network.EventWebSocketFrameSent:
+		r.frame(ev.RequestID, true, ev.Response)
+	case *network.EventWebSocketFrameReceived:
+		r.frame(ev.RequestID, false, ev.Response)
+	case *network.EventWebSocketFrameError:
+		if ws := r.sockets[ev.RequestID]; ws != nil {
+			ws.Error = ev.ErrorMessage
+		}
+	}
+}
+
+// frame adds a frame of the WebSocket id. r.mu must be held.
+func (r *webSocketRecorder) frame(id network.RequestID, sent bool, f *network.WebSocketFrame) {
+	ws := r.sockets[id]
+	if ws == nil || f == nil {
+		return
+	}
+	if len(ws.Frames) >= r.max {
+		ws.Dropped++
+		return
+	}
+	// the event timestamps are monotonic, not wall clock time
+	frame := WebSocketFrame{Time: time.Now(), Sent: sent, Opcode: int(f.Opcode), Data: []byte(f.PayloadData)}
+	if frame.Opcode != 1 {
+		// anything but text is base64 encoded
+		if data, err := base64.StdEncoding.DecodeString(f.PayloadData); err == nil {
+			frame.Data = data
+		}
+	}
+	ws.Frames = append(ws.Frames, frame)
+}
+
+// list returns the WebSockets recorded so far, in the order they were
+// opened.
+func (r *webSocketRecorder) list() []WebSocketMeta {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sockets := make([]WebSocketMeta, 0, len(r.order))
+	for _, id := range r.order {
+		ws := *r.sockets[id]
+		ws.Frames = append([]WebSocketFrame(nil), ws.Frames...)
+		sockets = append(sockets, ws)
+	}
+	return sockets
+}
+
+func sortedHeaders(headers network.Headers) []Header {
+	var list []Header
+	for name, value := range headers {
+		list = append(list, Header{Name: name, Value: fmt.Sprint(value)})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
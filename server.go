@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// server answers screenshot requests over HTTP using the already running
+// browser.
+type server struct {
+	c      *screenshot.Capturer
+	log    *slog.Logger
+	output string
+	ext    string
+	namer  *screenshot.Namer
+	stats  *metrics
+	claims *pathClaims
+	// sem limits how many captures run at once
+	sem chan struct{}
+}
+
+// screenshotRequest is the body of POST /screenshot. If Save is set the
+// screenshot is written to the output directory and the result is returned as
+// JSON, otherwise the image itself is returned.
+type screenshotRequest struct {
+	URL  string `json:"url"`
+	Save bool   `json:"save"`
+}
+
+func newServer(c *screenshot.Capturer, logger *slog.Logger, output, ext string, namer *screenshot.Namer, stats *metrics, concurrency int) *server {
+	return &server{
+		c:      c,
+		log:    logger,
+		output: output,
+		ext:    ext,
+		namer:  namer,
+		stats:  stats,
+		claims: newPathClaims(),
+		sem:    make(chan struct{}, concurrency),
+	}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /screenshot", s.handleScreenshot)
+	mux.Handle("GET /metrics", s.stats)
+	return mux
+}
+
+func (s *server) handleScreenshot(w http.ResponseWriter, r *http.Request) {
+	var req screenshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body must be {\"url\": ...}"})
+		return
+	}
+
+	if !s.acquire(r.Context()) {
+		return
+	}
+	defer s.release()
+	res, err := s.capture(r.Context(), req.URL, req.Save)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, res)
+		return
+	}
+
+	if req.Save {
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(res.Image))
+	w.Write(res.Image)
+}
+
+// acquire waits for a capture to be allowed to start, false if ctx was done
+// first. release must be called once the capture finished.
+func (s *server) acquire(ctx context.Context) bool {
+	s.stats.queue()
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		s.stats.cancel()
+		return false
+	}
+}
+
+func (s *server) release() {
+	<-s.sem
+}
+
+// capture captures requestURL for a request, also writing it to the output
+// directory if saveIt is set. The error is that of res.
+func (s *server) capture(ctx context.Context, requestURL string, saveIt bool) (result, error) {
+	shot, err := s.c.Capture(ctx, requestURL)
+	res := result{Result: shot}
+	var saveErr error
+	if err == nil && saveIt {
+		saveErr = save(s.output, s.ext, s.namer, s.claims, &res)
+	}
+	logResult(s.log, &res, err, saveErr)
+	s.stats.done(&res, err, saveErr)
+	if err := errors.Join(err, saveErr); err != nil {
+		res.Error = err.Error()
+		return res, err
+	}
+	return res, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// Sinks of -sink.
+const (
+	sinkFS      = "fs"
+	sinkS3      = "s3"
+	sinkSQLite  = "sqlite"
+	sinkStdout  = "stdout"
+	sinkWebhook = "webhook"
+)
+
+// outputSink is where the captures of a run go. Every result is written to
+// the sinks in turn, the fs sink first, so the files it saves are there for
+// those after it, like s3 uploading them.
+type outputSink interface {
+	// write stores a finished capture. Failed ones are written as well,
+	// with the error in res.Error.
+	write(res *result) error
+	// finish is called once the run is done, with all its results and
+	// those kept from earlier runs with -resume.
+	finish(results []result, interrupted bool) error
+}
+
+// parseSinks checks the sinks given to -sink, the filesystem if none are.
+// The fs sink always comes first.
+func parseSinks(raw []string) ([]string, error) {
+	if len(raw) == 0 {
+		return []string{sinkFS}, nil
+	}
+	var sinks []string
+	seen := map[string]bool{}
+	for _, s := range raw {
+		switch s {
+		case sinkFS, sinkS3, sinkSQLite, sinkStdout, sinkWebhook:
+		default:
+			return nil, fmt.Errorf("unknown sink %q, must be one of fs, s3, sqlite, stdout or webhook", s)
+		}
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		if s == sinkFS {
+			sinks = append([]string{s}, sinks...)
+		} else {
+			sinks = append(sinks, s)
+		}
+	}
+	return sinks, nil
+}
+
+// hasSink reports whether sink is one of sinks.
+func hasSink(sinks []string, sink string) bool {
+	for _, s := range sinks {
+		if s == sink {
+			return true
+		}
+	}
+	return false
+}
+
+// openSinks returns the sinks of b for a run into output.
+func (b *batch) openSinks(output string) ([]outputSink, error) {
+	var sinks []outputSink
+	for _, name := range b.sinks {
+		switch name {
+		case sinkFS:
+			rw, err := newResultWriter(output, b.jsonOut, b.resume)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, &fsSink{
+				output:     output,
+				ext:        b.ext,
+				namer:      b.namer,
+				thumbWidth: b.thumbWidth,
+				ocr:        b.ocr,
+				rw:         rw,
+				claims:     newPathClaims(),
+			})
+		case sinkS3:
+			sinks = append(sinks, &s3Sink{up: b.uploader, output: output, results: !b.jsonOut})
+		case sinkSQLite:
+			runID, err := b.db.startRun(output)
+			if err != nil {
+				return nil, fmt.Errorf("recording run: %w", err)
+			}
+			sinks = append(sinks, &sqliteSink{db: b.db, run: runID})
+		case sinkStdout:
+			sinks = append(sinks, &stdoutSink{enc: json.NewEncoder(os.Stdout)})
+		case sinkWebhook:
+			sinks = append(sinks, &webhookSink{url: b.webhook, client: &http.Client{Timeout: 30 * time.Second}})
+		}
+	}
+	return sinks, nil
+}
+
+// fsSink saves the screenshots and other files of every page into the output
+// directory, with results.jsonl and the gallery.
+type fsSink struct {
+	output     string
+	ext        string
+	namer      *screenshot.Namer
+	thumbWidth int
+	ocr        *ocr
+	rw         *resultWriter
+	claims     *pathClaims
+}
+
+func (s *fsSink) write(res *result) error {
+	var err error
+	switch {
+	case res.Error == "" && !res.Unchanged, res.Error != "" && res.Image != nil:
+		// failed pages have an image with -capture-errors
+		err = save(s.output, s.ext, s.namer, s.claims, res)
+	}
+	if err == nil && res.Error == "" && s.thumbWidth > 0 && !res.Unchanged {
+		err = writeThumbnail(s.output, s.thumbWidth, res)
+	}
+	if err == nil && res.Error == "" && s.ocr != nil && !res.Unchanged {
+		err = s.ocr.extract(context.Background(), s.output, res)
+	}
+	if err != nil {
+		res.Error = errors.Join(resultError(res), err).Error()
+	}
+	return errors.Join(err, s.rw.write(res))
+}
+
+func (s *fsSink) finish(results []result, interrupted bool) error {
+	return errors.Join(s.rw.close(), writeGallery(s.output, results))
+}
+
+// s3Sink uploads the files the fs sink saved to object storage.
+type s3Sink struct {
+	up     *uploader
+	output string
+	// results is set if results.jsonl is written
+	results bool
+}
+
+func (s *s3Sink) write(res *result) error {
+	if !res.Unchanged {
+		s.up.upload(s.output, res.files()...)
+	}
+	return nil
+}
+
+func (s *s3Sink) finish(results []result, interrupted bool) error {
+	files := []string{"index.html"}
+	if s.results {
+		files = append(files, "results.jsonl")
+	}
+	if interrupted {
+		files = append(files, "checkpoint.txt")
+	}
+	s.up.uploadKeep(s.output, files...)
+	s.up.wait()
+	return nil
+}
+
+// sqliteSink records every capture in the -db database.
+type sqliteSink struct {
+	db  *resultDB
+	run int64
+}
+
+func (s *sqliteSink) write(res *result) error {
+	if err := s.db.insert(s.run, res); err != nil {
+		return fmt.Errorf("recording result: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteSink) finish(results []result, interrupted bool) error {
+	return nil
+}
+
+// embeddedResult is a result with its screenshot, which encoding/json writes
+// base64 encoded.
+type embeddedResult struct {
+	*result
+	Image     []byte `json:"image,omitempty"`
+	ImageType string `json:"image_type,omitempty"`
+}
+
+func embed(res *result) embeddedResult {
+	e := embeddedResult{result: res, Image: res.Image}
+	if len(res.Image) > 0 {
+		e.ImageType = http.DetectContentType(res.Image)
+	}
+	return e
+}
+
+// stdoutSink writes every result as a JSON line to stdout, with the
+// screenshot base64 encoded in it.
+type stdoutSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (s *stdoutSink) write(res *result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(embed(res))
+}
+
+func (s *stdoutSink) finish(results []result, interrupted bool) error {
+	return nil
+}
+
+// webhookSink posts every result as JSON to a URL, with the screenshot base64
+// encoded in it.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *webhookSink) write(res *result) error {
+	body, err := json.Marshal(embed(res))
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("posting result: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("posting result: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *webhookSink) finish(results []result, interrupted bool) error {
+	return nil
+}
+
+// resultError returns the error recorded in res, if any.
+func resultError(res *result) error {
+	if res.Error == "" {
+		return nil
+	}
+	return errors.New(res.Error)
+}
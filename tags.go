@@ -0,0 +1,82 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// splitTags splits a line of plain input like "https://example.com
+// client-a,web" into the URL and its tags. Lines without tags are returned
+// as they are.
+func splitTags(line string) (string, []string) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return line, nil
+	}
+	return fields[0], parseTags(strings.Join(fields[1:], ","))
+}
+
+// parseTags parses a comma separated list of tags.
+func parseTags(raw string) []string {
+	var tags []string
+	for _, tag := range strings.Split(raw, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// mergeTags returns the tags of all lists in order, each once.
+func mergeTags(lists ...[]string) []string {
+	var tags []string
+	for _, list := range lists {
+		for _, tag := range list {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// tagIndex holds the tags of the URLs of a run given in plain input, or those
+// inherited from the page a URL was found on. It is safe for concurrent use.
+type tagIndex struct {
+	mu   sync.Mutex
+	tags map[string][]string
+}
+
+func newTagIndex() *tagIndex {
+	return &tagIndex{tags: map[string][]string{}}
+}
+
+// set sets the tags of requestURL, as given in the input.
+func (ti *tagIndex) set(requestURL string, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	ti.tags[requestURL] = tags
+}
+
+// inherit gives requestURL, found on a page with tags, the same tags unless
+// it has its own.
+func (ti *tagIndex) inherit(requestURL string, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	if _, ok := ti.tags[requestURL]; !ok {
+		ti.tags[requestURL] = tags
+	}
+}
+
+func (ti *tagIndex) get(requestURL string) []string {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	return ti.tags[requestURL]
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// Structured input formats, where every URL can have its own options.
+const (
+	inputJSON = "json"
+	inputCSV  = "csv"
+)
+
+// target is a URL of structured input with the options to capture it with.
+// In CSV input the columns are named like the JSON fields, and the headers
+// are given one per line.
+type target struct {
+	URL     string            `json:"url"`
+	Width   int64             `json:"width,omitempty"`
+	Height  int64             `json:"height,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Cookies are like -cookie, "name=value; name2=value2"
+	Cookies string `json:"cookies,omitempty"`
+	// Delay is a duration like 2s
+	Delay   string `json:"delay,omitempty"`
+	WaitFor string `json:"wait_for,omitempty"`
+	// Name is the path to save the screenshot and everything else captured
+	// at, relative to the output directory and without extension, instead
+	// of the -filename-template one.
+	Name string `json:"name,omitempty"`
+	// Tags are added to the result, in CSV comma separated
+	Tags []string `json:"tags,omitempty"`
+
+	opts screenshot.URLOptions
+}
+
+// parseTargets reads structured input in format, a JSON array of targets or
+// one per line, or CSV with a header row.
+func parseTargets(format string, r io.Reader) ([]*target, error) {
+	var targets []*target
+	var err error
+	switch format {
+	case inputJSON:
+		targets, err = parseTargetsJSON(r)
+	case inputCSV:
+		targets, err = parseTargetsCSV(r)
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range targets {
+		if err := t.parse(); err != nil {
+			return nil, fmt.Errorf("reading %s input: target %d: %w", format, i+1, err)
+		}
+	}
+	return targets, nil
+}
+
+func parseTargetsJSON(r io.Reader) ([]*target, error) {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(br)
+	var targets []*target
+	if first == '[' {
+		if err := dec.Decode(&targets); err != nil {
+			return nil, fmt.Errorf("reading json input: %w", err)
+		}
+		return targets, nil
+	}
+	for {
+		var t target
+		if err := dec.Decode(&t); errors.Is(err, io.EOF) {
+			return targets, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("reading json input: %w", err)
+		}
+		targets = append(targets, &t)
+	}
+}
+
+func parseTargetsCSV(r io.Reader) ([]*target, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv input: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	var targets []*target
+	for n, rec := range records[1:] {
+		t := &target{}
+		for i, col := range header {
+			value := strings.TrimSpace(rec[i])
+			if value == "" {
+				continue
+			}
+			switch strings.ToLower(strings.TrimSpace(col)) {
+			case "url":
+				t.URL = value
+			case "width", "height":
+				v, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("reading csv input: line %d: invalid %s %q", n+2, col, value)
+				}
+				if col == "width" {
+					t.Width = v
+				} else {
+					t.Height = v
+				}
+			case "headers":
+				if t.Headers, err = parseHeaders(strings.Split(value, "\n")); err != nil {
+					return nil, fmt.Errorf("reading csv input: line %d: %w", n+2, err)
+				}
+			case "cookies":
+				t.Cookies = value
+			case "delay":
+				t.Delay = value
+			case "wait_for":
+				t.WaitFor = value
+			case "name":
+				t.Name = value
+			case "tags":
+				t.Tags = parseTags(value)
+			default:
+				return nil, fmt.Errorf("reading csv input: unknown column %q", col)
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// parse checks t and sets its options.
+func (t *target) parse() error {
+	if t.URL == "" {
+		return fmt.Errorf("no url")
+	}
+	if t.Width < 0 || t.Height < 0 {
+		return fmt.Errorf("invalid viewport %dx%d", t.Width, t.Height)
+	}
+	if t.Name != "" {
+		name := filepath.Clean(filepath.FromSlash(t.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("name %q is outside of the output directory", t.Name)
+		}
+		t.Name = name
+	}
+	t.opts = screenshot.URLOptions{Width: t.Width, Height: t.Height, Headers: t.Headers, WaitFor: t.WaitFor, Tags: t.Tags}
+	if t.Cookies != "" {
+		cookies, err := parseCookies([]string{t.Cookies})
+		if err != nil {
+			return err
+		}
+		t.opts.Cookies = cookies
+	}
+	if t.Delay != "" {
+		d, err := time.ParseDuration(t.Delay)
+		if err != nil {
+			return fmt.Errorf("invalid delay: %w", err)
+		}
+		t.opts.Delay = d
+	}
+	return nil
+}
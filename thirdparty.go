@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// thirdPartyUsage is a third party site across the pages of one or more runs.
+type thirdPartyUsage struct {
+	Domain   string `json:"domain"`
+	Category string `json:"category,omitempty"`
+	// Pages is how many distinct pages requested it, Sites the hosts of
+	// those pages.
+	Pages    int      `json:"pages"`
+	Sites    []string `json:"sites"`
+	Runs     int      `json:"runs"`
+	Requests int      `json:"requests"`
+	Hosts    []string `json:"hosts"`
+	// FirstSeen and LastSeen are when the first and last page requesting
+	// it were captured.
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// runThirdParties implements the third-parties subcommand, summarizing the
+// third party sites recorded with -third-parties in the results of output
+// directories. Directories without results, like the output of -interval,
+// stand for the runs in their subdirectories.
+func runThirdParties(args []string) error {
+	fset := flag.NewFlagSet("third-parties", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: screenshot third-parties [flags] <output dir>...")
+		fset.PrintDefaults()
+	}
+	var jsonOut bool
+	fset.BoolVar(&jsonOut, "json", false, "If true, writes the summary as a JSON array instead of a table")
+	var category string
+	fset.StringVar(&category, "category", "", "only list third parties of this category, e.g. analytics, or \"none\" for those without one")
+	fset.Parse(args)
+	if fset.NArg() == 0 {
+		fset.Usage()
+		os.Exit(2)
+	}
+
+	var runs []string
+	for _, dir := range fset.Args() {
+		found, err := findRuns(dir)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, found...)
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no results.jsonl in %s", strings.Join(fset.Args(), ", "))
+	}
+
+	usage := map[string]*thirdPartyUsage{}
+	pages := map[string]map[string]bool{}
+	sites := map[string]map[string]bool{}
+	runsOf := map[string]map[string]bool{}
+	hosts := map[string]map[string]bool{}
+	for _, run := range runs {
+		results, err := loadResults(run)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			site := redirectHost(r.FinalURL)
+			if site == "" {
+				site = redirectHost(r.URL)
+			}
+			for _, tp := range r.ThirdParties {
+				u := usage[tp.Domain]
+				if u == nil {
+					u = &thirdPartyUsage{Domain: tp.Domain}
+					usage[tp.Domain] = u
+					pages[tp.Domain], sites[tp.Domain] = map[string]bool{}, map[string]bool{}
+					runsOf[tp.Domain], hosts[tp.Domain] = map[string]bool{}, map[string]bool{}
+				}
+				if tp.Category != "" {
+					u.Category = tp.Category
+				}
+				u.Requests += tp.Requests
+				pages[tp.Domain][r.URL] = true
+				sites[tp.Domain][site] = true
+				runsOf[tp.Domain][run] = true
+				for _, h := range tp.Hosts {
+					hosts[tp.Domain][h] = true
+				}
+				if u.FirstSeen.IsZero() || r.Started.Before(u.FirstSeen) {
+					u.FirstSeen = r.Started
+				}
+				if r.Started.After(u.LastSeen) {
+					u.LastSeen = r.Started
+				}
+			}
+		}
+	}
+
+	list := make([]thirdPartyUsage, 0, len(usage))
+	for domain, u := range usage {
+		if category != "" && u.Category != category && !(category == "none" && u.Category == "") {
+			continue
+		}
+		u.Pages, u.Runs = len(pages[domain]), len(runsOf[domain])
+		u.Sites, u.Hosts = sortedKeys(sites[domain]), sortedKeys(hosts[domain])
+		list = append(list, *u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Pages != list[j].Pages {
+			return list[i].Pages > list[j].Pages
+		}
+		return list[i].Domain < list[j].Domain
+	})
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DOMAIN\tCATEGORY\tPAGES\tSITES\tRUNS\tREQUESTS\tFIRST SEEN\tLAST SEEN")
+	for _, u := range list {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n", u.Domain, u.Category, u.Pages, len(u.Sites), u.Runs, u.Requests,
+			u.FirstSeen.Format(time.DateOnly), u.LastSeen.Format(time.DateOnly))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d third parties in %d runs\n", len(list), len(runs))
+	return nil
+}
+
+// findRuns returns dir if it has results, otherwise those of its
+// subdirectories that do.
+func findRuns(dir string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "results.jsonl")); err == nil {
+		return []string{dir}, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var runs []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, e.Name(), "results.jsonl")); err == nil {
+			runs = append(runs, filepath.Join(dir, e.Name()))
+		}
+	}
+	return runs, nil
+}
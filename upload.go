@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uploader copies finished files to object storage in the background,
+// retrying failed uploads and optionally deleting the local copies.
+type uploader struct {
+	client  *s3Client
+	prefix  string
+	root    string // local directory keys are relative to
+	retries int
+	remove  bool
+	logger  *slog.Logger
+
+	files   chan uploadFile
+	workers sync.WaitGroup
+	pending sync.WaitGroup
+}
+
+type uploadFile struct {
+	path string
+	// keep is set for files that must stay on disk even with remove, like
+	// results.jsonl which -resume reads
+	keep bool
+}
+
+// newUploader parses dest, like s3://bucket/prefix or gs://bucket/prefix, and
+// starts concurrency upload workers. Objects are named after the path of the
+// file relative to root.
+func newUploader(dest, endpoint, root string, concurrency int, remove bool, logger *slog.Logger) (*uploader, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload destination %q: %w", dest, err)
+	}
+	switch u.Scheme {
+	case "s3":
+	case "gs":
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+	default:
+		return nil, fmt.Errorf("invalid upload destination %q, must be like s3://bucket/prefix or gs://bucket/prefix", dest)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid upload destination %q, no bucket", dest)
+	}
+	client, err := newS3Client(u.Host, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	up := &uploader{
+		client:  client,
+		prefix:  strings.Trim(u.Path, "/"),
+		root:    root,
+		retries: 3,
+		remove:  remove,
+		logger:  logger,
+		files:   make(chan uploadFile, 64),
+	}
+	for i := 0; i < max(concurrency, 1); i++ {
+		up.workers.Add(1)
+		go up.work()
+	}
+	return up, nil
+}
+
+// upload queues files, relative to dir, for uploading.
+func (up *uploader) upload(dir string, files ...string) {
+	up.queue(dir, false, files...)
+}
+
+// uploadKeep queues files like upload, never deleting the local copies.
+func (up *uploader) uploadKeep(dir string, files ...string) {
+	up.queue(dir, true, files...)
+}
+
+func (up *uploader) queue(dir string, keep bool, files ...string) {
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+		up.pending.Add(1)
+		up.files <- uploadFile{path: filepath.Join(dir, f), keep: keep}
+	}
+}
+
+// wait blocks until every queued file is uploaded or failed.
+func (up *uploader) wait() {
+	up.pending.Wait()
+}
+
+// close waits for the queued uploads and stops the workers.
+func (up *uploader) close() {
+	up.wait()
+	close(up.files)
+	up.workers.Wait()
+}
+
+func (up *uploader) work() {
+	defer up.workers.Done()
+	for f := range up.files {
+		if err := up.put(f.path); err != nil {
+			up.logger.Error("upload failed", "path", f.path, "err", err)
+		} else if up.remove && !f.keep {
+			if err := os.Remove(f.path); err != nil {
+				up.logger.Error("removing uploaded file", "path", f.path, "err", err)
+			}
+		}
+		up.pending.Done()
+	}
+}
+
+// put uploads the file at p, retrying with a growing backoff.
+func (up *uploader) put(p string) error {
+	rel, err := filepath.Rel(up.root, p)
+	if err != nil {
+		return err
+	}
+	key := path.Join(up.prefix, filepath.ToSlash(rel))
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(p))
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		err = up.client.put(key, contentType, data)
+		if err == nil || attempt >= up.retries {
+			return err
+		}
+		up.logger.Debug("retrying upload", "key", key, "err", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/AlfredBerg/screenshot/screenshot"
+)
+
+// runVerify implements the verify subcommand, capturing the URLs of a
+// baseline output directory again and failing if any page looks different
+// by more than a threshold, for visual regression tests.
+func runVerify(args []string) error {
+	fset := flag.NewFlagSet("verify", flag.ExitOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: screenshot verify [flags] <baseline output dir>")
+		fset.PrintDefaults()
+	}
+	opts := screenshot.DefaultOptions()
+	var report string
+	fset.StringVar(&report, "o", "verify", "directory to write the new screenshots and the report of the differing ones to")
+	var threshold float64
+	fset.Float64Var(&threshold, "threshold", 0.02, "fraction (0-1) of pixels that may differ from the baseline for a page to pass")
+	var concurrency int
+	fset.IntVar(&concurrency, "c", 2, "concurrency level")
+	fset.BoolVar(&opts.FullPage, "fullpage", opts.FullPage, "If true, captures the entire scroll height of the page instead of only the viewport, as the baseline should have been")
+	fset.Int64Var(&opts.Width, "width", opts.Width, "viewport width")
+	fset.Int64Var(&opts.Height, "height", opts.Height, "viewport height")
+	fset.DurationVar(&opts.Delay, "delay", 0, "extra time to wait after the page has loaded before capturing")
+	fset.StringVar(&opts.ChromePath, "chrome-path", "", "Chrome or Chromium binary to use instead of the one found in the usual places")
+	fset.Parse(args)
+	if fset.NArg() != 1 {
+		fset.Usage()
+		os.Exit(2)
+	}
+	baseline := fset.Arg(0)
+	if threshold < 0 || threshold > 1 {
+		return fmt.Errorf("invalid -threshold %g, must be between 0 and 1", threshold)
+	}
+
+	results, err := loadResults(baseline)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no screenshots in %s/results.jsonl", baseline)
+	}
+	if err := baselineOptions(baseline, results, &opts); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(report, 0755); err != nil {
+		return err
+	}
+
+	c, err := screenshot.New(opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// every page of the baseline, also the variants of -also-ip or -ports
+	// of the same URL, is verified by its file name
+	pages := make(chan result)
+	go func() {
+		defer close(pages)
+		for _, r := range results {
+			pages <- r
+		}
+	}()
+	var mu sync.Mutex
+	var diffs []pageDiff
+	var failed []string
+	var wg sync.WaitGroup
+	for i := 0; i < max(concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for base := range pages {
+				requestURL, o := verifyTarget(base)
+				shot, err := c.With(o).Capture(context.Background(), requestURL)
+				d, err := verifyPage(baseline, report, opts.Format.Extension(), base, shot, err)
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					fmt.Fprintf(os.Stderr, "%s: %s\n", base.Screenshot, err)
+					failed = append(failed, base.Screenshot)
+				case d.Pixels > threshold:
+					fmt.Fprintf(os.Stderr, "%s: %.2f%% of pixels differ\n", base.Screenshot, 100*d.Pixels)
+					diffs = append(diffs, d)
+					failed = append(failed, base.Screenshot)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Pixels > diffs[j].Pixels })
+	f, err := os.Create(filepath.Join(report, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := diffTemplate.Execute(f, diffs); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d pages differ from the baseline or failed, report written to %s", len(failed), len(results), f.Name())
+	}
+	fmt.Printf("all %d pages match the baseline\n", len(results))
+	return nil
+}
+
+// verifyTarget returns the URL to capture base again from, the input if it
+// had no scheme, and the Host header of an -also-ip variant.
+func verifyTarget(base result) (string, screenshot.URLOptions) {
+	var o screenshot.URLOptions
+	if base.IPVariant == variantHost {
+		if u, err := url.Parse(base.VariantOf); err == nil {
+			o.HostHeader = u.Host
+		}
+	}
+	if base.Input != "" {
+		return base.Input, o
+	}
+	return base.URL, o
+}
+
+// baselineOptions carries the format, quality, scale and device of the run
+// that wrote baseline over to opts, from its run.json, or else the format
+// from the extension of its screenshots.
+func baselineOptions(baseline string, results []result, opts *screenshot.Options) error {
+	m, err := readManifest(baseline)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		ext := filepath.Ext(results[0].Screenshot)
+		for _, f := range []screenshot.Format{screenshot.FormatPNG, screenshot.FormatJPEG, screenshot.FormatWebP} {
+			if f.Extension() == ext {
+				opts.Format = f
+			}
+		}
+		return nil
+	}
+	if v := m.Flags["format"]; v != "" {
+		opts.Format = screenshot.Format(v)
+	}
+	if v := m.Flags["quality"]; v != "" {
+		if opts.Quality, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("reading -quality of %s/run.json: %w", baseline, err)
+		}
+	}
+	if v := m.Flags["scale"]; v != "" {
+		if opts.Scale, err = strconv.ParseFloat(v, 64); err != nil {
+			return fmt.Errorf("reading -scale of %s/run.json: %w", baseline, err)
+		}
+	}
+	opts.Device = m.Flags["device"]
+	return nil
+}
+
+// verifyPage writes the newly captured shot of the baseline result base to
+// the report directory and compares the two, writing the diff image as well
+// if they differ. Paths of the returned diff are relative to report.
+func verifyPage(baseline, report, ext string, base result, shot screenshot.Result, err error) (pageDiff, error) {
+	if err != nil {
+		return pageDiff{}, err
+	}
+	old, err := os.ReadFile(filepath.Join(baseline, base.Screenshot))
+	if err != nil {
+		return pageDiff{}, err
+	}
+	stem := strings.TrimSuffix(base.Screenshot, filepath.Ext(base.Screenshot))
+	name := stem + ".new" + ext
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(report, name)), 0755); err != nil {
+		return pageDiff{}, err
+	}
+	if err := os.WriteFile(filepath.Join(report, name), shot.Image, 0644); err != nil {
+		return pageDiff{}, err
+	}
+	cmp, err := screenshot.Compare(old, shot.Image)
+	if err != nil {
+		return pageDiff{}, fmt.Errorf("comparing: %w", err)
+	}
+
+	d := pageDiff{Name: base.Screenshot, New: name, Pixels: cmp.Pixels, Distance: cmp.Distance}
+	if oldPath, err := filepath.Abs(filepath.Join(baseline, base.Screenshot)); err == nil {
+		absReport, _ := filepath.Abs(report)
+		if rel, err := filepath.Rel(absReport, oldPath); err == nil {
+			d.Old = filepath.ToSlash(rel)
+		} else {
+			d.Old = oldPath
+		}
+	}
+	if cmp.Pixels > 0 {
+		d.Diff = stem + ".diff.png"
+		if err := writePNG(filepath.Join(report, d.Diff), cmp); err != nil {
+			return pageDiff{}, err
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchSettle is how long the input file must be left alone after a change
+// before it is read again, as tools often write it in several steps.
+const watchSettle = 500 * time.Millisecond
+
+// inputWatcher follows the changes of the input file of monitor, so URLs
+// added to it are captured without waiting for the next round and removed
+// ones are no longer captured. It is safe for concurrent use.
+type inputWatcher struct {
+	path    string
+	logger  *slog.Logger
+	watcher *fsnotify.Watcher
+	// changed is signalled once the file was changed and read again
+	changed chan struct{}
+
+	mu sync.Mutex
+	// lines are those of the file as last read, in order, current their
+	// URLs as a set along with the normalized ones, and known the URLs
+	// captured in the round, which may have been removed since
+	lines   []string
+	current map[string]bool
+	known   map[string]bool
+}
+
+// newInputWatcher starts watching the file at path. The directory is watched
+// rather than the file, which editors and tools often replace.
+func newInputWatcher(path string, logger *slog.Logger) (*inputWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	w := &inputWatcher{
+		path:    filepath.Clean(path),
+		logger:  logger,
+		watcher: watcher,
+		changed: make(chan struct{}, 1),
+	}
+	go w.watch()
+	return w, nil
+}
+
+func (w *inputWatcher) watch() {
+	var settle <-chan time.Time
+	for {
+		select {
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) == w.path && ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				settle = time.After(watchSettle)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Warn("watching input", "err", err)
+		case <-settle:
+			settle = nil
+			if err := w.read(); err != nil {
+				w.logger.Warn("reading changed input", "err", err)
+				continue
+			}
+			select {
+			case w.changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// read reads the lines of the file.
+func (w *inputWatcher) read() error {
+	f, err := os.Open(w.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var lines []string
+	current := map[string]bool{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+		requestURL, _ := splitTags(line)
+		current[requestURL] = true
+		current[normalizeURL(requestURL)] = true
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines, w.current = lines, current
+	return nil
+}
+
+// start reads the file as a round of monitor starts, all of whose lines it
+// captures.
+func (w *inputWatcher) start() error {
+	if err := w.read(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.known = make(map[string]bool, len(w.current))
+	for line := range w.current {
+		w.known[line] = true
+	}
+	return nil
+}
+
+// added returns the lines added since they were last asked for or the
+// round started.
+func (w *inputWatcher) added() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var added []string
+	for _, line := range w.lines {
+		requestURL, _ := splitTags(line)
+		if !w.known[requestURL] {
+			added = append(added, line)
+			w.known[requestURL] = true
+			w.known[normalizeURL(requestURL)] = true
+		}
+	}
+	return added
+}
+
+// removed reports whether requestURL, as read from the file or normalized,
+// was removed from it during the round. Pages found by crawling never were
+// in it.
+func (w *inputWatcher) removed(requestURL string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.known[requestURL] && !w.current[requestURL]
+}
+
+func (w *inputWatcher) close() error {
+	return w.watcher.Close()
+}